@@ -0,0 +1,141 @@
+package applink
+
+import (
+	"strings"
+)
+
+// A single al:* meta tag, like al:ios:url.
+type Property struct {
+	Name    string
+	Content string
+}
+
+// The properties each platform has. Anything else is ignored, so a typo
+// doesn't leave an empty entry behind.
+var platformProperties = map[string]map[string]bool{
+	"ios":               {"url": true, "app_store_id": true, "app_name": true},
+	"iphone":            {"url": true, "app_store_id": true, "app_name": true},
+	"ipad":              {"url": true, "app_store_id": true, "app_name": true},
+	"android":           {"url": true, "package": true, "class": true, "app_name": true},
+	"windows_phone":     {"url": true, "app_id": true, "app_name": true},
+	"windows":           {"url": true, "app_id": true, "app_name": true},
+	"windows_universal": {"url": true, "app_id": true, "app_name": true},
+	"web":               {"url": true, "should_fallback": true},
+}
+
+// Builds app link data out of al:* properties in the order they appear on
+// the page. When a property shows up again for a platform, it starts a new
+// entry for that platform, which is how the spec lets a page list more than
+// one app. Returns nil if there aren't any properties we understand.
+func FromProperties(properties []Property) *AppLink {
+	var appLink AppLink
+	found := false
+	// The properties we've seen for the current entry of each platform.
+	seen := make(map[string]map[string]bool)
+	for _, property := range properties {
+		parts := strings.SplitN(strings.ToLower(strings.TrimSpace(property.Name)), ":", 3)
+		if len(parts) != 3 || parts[0] != "al" {
+			continue
+		}
+		platform, name := parts[1], parts[2]
+		if !platformProperties[platform][name] {
+			continue
+		}
+		content := strings.TrimSpace(property.Content)
+		newEntry := seen[platform] == nil || seen[platform][name]
+		if newEntry {
+			seen[platform] = make(map[string]bool)
+		}
+		appLink.set(platform, name, content, newEntry)
+		seen[platform][name] = true
+		found = true
+	}
+	if !found {
+		return nil
+	}
+	return &appLink
+}
+
+// Sets a single property, starting a new entry for the platform if asked
+// to. The property has to be one of platformProperties.
+func (a *AppLink) set(platform, name, content string, newEntry bool) {
+	switch platform {
+	case "ios":
+		if newEntry || len(a.Ios) == 0 {
+			a.Ios = append(a.Ios, &Ios{})
+		}
+		ios := a.Ios[len(a.Ios)-1]
+		setIos(&ios.Url, &ios.AppStoreId, &ios.AppName, name, content)
+	case "iphone":
+		if newEntry || len(a.Iphone) == 0 {
+			a.Iphone = append(a.Iphone, &Iphone{})
+		}
+		iphone := a.Iphone[len(a.Iphone)-1]
+		setIos(&iphone.Url, &iphone.AppStoreId, &iphone.AppName, name, content)
+	case "ipad":
+		if newEntry || len(a.Ipad) == 0 {
+			a.Ipad = append(a.Ipad, &Ipad{})
+		}
+		ipad := a.Ipad[len(a.Ipad)-1]
+		setIos(&ipad.Url, &ipad.AppStoreId, &ipad.AppName, name, content)
+	case "android":
+		if newEntry || len(a.Android) == 0 {
+			a.Android = append(a.Android, &Android{})
+		}
+		android := a.Android[len(a.Android)-1]
+		switch name {
+		case "url":
+			android.Url = content
+		case "package":
+			android.Package = content
+		case "class":
+			android.Class = content
+		case "app_name":
+			android.AppName = content
+		}
+	case "windows_phone":
+		setWindows(&a.WindowsPhone, name, content, newEntry)
+	case "windows":
+		setWindows(&a.Windows, name, content, newEntry)
+	case "windows_universal":
+		setWindows(&a.WindowsUniversal, name, content, newEntry)
+	case "web":
+		if a.Web == nil {
+			// The spec says to fall back to the web unless told otherwise.
+			a.Web = &Web{ShouldFallback: true}
+		}
+		switch name {
+		case "url":
+			a.Web.Url = content
+		case "should_fallback":
+			a.Web.ShouldFallback = content != "false" && content != "0"
+		}
+	}
+}
+
+// All the iOS flavors have the same properties.
+func setIos(url, appStoreId, appName *string, name, content string) {
+	switch name {
+	case "url":
+		*url = content
+	case "app_store_id":
+		*appStoreId = content
+	case "app_name":
+		*appName = content
+	}
+}
+
+func setWindows(entries *[]*Windows, name, content string, newEntry bool) {
+	if newEntry || len(*entries) == 0 {
+		*entries = append(*entries, &Windows{})
+	}
+	windows := (*entries)[len(*entries)-1]
+	switch name {
+	case "url":
+		windows.Url = content
+	case "app_id":
+		windows.AppId = content
+	case "app_name":
+		windows.AppName = content
+	}
+}
@@ -0,0 +1,26 @@
+package applink
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFromPropertiesIgnoresUnknownProperties(t *testing.T) {
+	t.Parallel()
+	appLink := FromProperties([]Property{
+		{"al:android:app_store_id", "x"},
+		{"al:ios:url", "ios://a"},
+		{"al:web:shuold_fallback", "false"},
+		{"al:blackberry:url", "bb://a"},
+	})
+	expected := &AppLink{
+		Ios: []*Ios{{Url: "ios://a"}},
+	}
+	if !reflect.DeepEqual(appLink, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, appLink)
+	}
+
+	if appLink := FromProperties([]Property{{"al:android:app_store_id", "x"}}); appLink != nil {
+		t.Errorf("Expected no app links from only unknown properties, got %+v", appLink)
+	}
+}
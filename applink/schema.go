@@ -2,31 +2,31 @@
 package applink
 
 type Ios struct {
-	Url string `json:"url" ogtag:"al:ios:url"`
+	Url string `json:"url"`
 	// The only evidence i have that this is a string is here:
 	// https://github.com/BoltsFramework/Bolts-iOS/blob/b72d5f2d6e0c418beea0a48da540a9eaf0768c0b/Bolts/iOS/BFAppLinkTarget.h#L28
-	AppStoreId string `json:"app_store_id,omitempty" ogtag:"al:ios:app_store_id"`
-	AppName    string `json:"app_name,omitempty" ogtag:"al:ios:app_name"`
+	AppStoreId string `json:"app_store_id,omitempty"`
+	AppName    string `json:"app_name,omitempty"`
 }
 
 type Iphone struct {
-	Url string `json:"url" ogtag:"al:iphone:url"`
+	Url string `json:"url"`
 	// The only evidence i have that this is a string is here:
 	// https://github.com/BoltsFramework/Bolts-iOS/blob/b72d5f2d6e0c418beea0a48da540a9eaf0768c0b/Bolts/iOS/BFAppLinkTarget.h#L28
-	AppStoreId string `json:"app_store_id,omitempty" ogtag:"al:iphone:app_store_id"`
-	AppName    string `json:"app_name,omitempty" ogtag:"al:iphone:app_name"`
+	AppStoreId string `json:"app_store_id,omitempty"`
+	AppName    string `json:"app_name,omitempty"`
 }
 
 type Ipad struct {
-	Url string `json:"url" ogtag:"al:ipad:url"`
+	Url string `json:"url"`
 	// The only evidence i have that this is a string is here:
 	// https://github.com/BoltsFramework/Bolts-iOS/blob/b72d5f2d6e0c418beea0a48da540a9eaf0768c0b/Bolts/iOS/BFAppLinkTarget.h#L28
-	AppStoreId string `json:"app_store_id,omitempty" ogtag:"al:ipad:app_store_id"`
-	AppName    string `json:"app_name,omitempty" ogtag:"al:ipad:app_name"`
+	AppStoreId string `json:"app_store_id,omitempty"`
+	AppName    string `json:"app_name,omitempty"`
 }
 
 type Android struct {
-	Url     string `json:"url,omitempty" ogtag:"al:android:url"`
+	Url     string `json:"url,omitempty"`
 	Package string `json:"package,omitempty"`
 	Class   string `json:"class,omitempty"`
 	AppName string `json:"app_name,omitempty"`
@@ -40,17 +40,18 @@ type Windows struct {
 
 type Web struct {
 	Url            string `json:"url,omitempty"`
-	ShouldFallback bool   `json:"should_fallback,omitempty"`
+	ShouldFallback bool   `json:"should_fallback"`
 }
 
+// A page can list more than one app per platform, in order of preference,
+// so each platform gets a list. There's only ever one web fallback.
 type AppLink struct {
-	Ios     *Ios     `json:"ios,omitempty" ogtag:",fill"`
-	Iphone  *Iphone  `json:"iphone,omitempty" ogtag:",fill"`
-	Ipad    *Ipad    `json:"ipad,omitempty" ogtag:",fill"`
-	Android *Android `json:"android,omitempty" ogtag:",fill"`
-	// TODO: Windows/web support
-	WindowsPhone     *Windows `json:"windows_phone,omitempty"`
-	Windows          *Windows `json:"windows,omitempty"`
-	WindowsUniversal *Windows `json:"windows_universal,omitempty"`
-	Web              *Web     `json:"web,omitempty"`
+	Ios              []*Ios     `json:"ios,omitempty"`
+	Iphone           []*Iphone  `json:"iphone,omitempty"`
+	Ipad             []*Ipad    `json:"ipad,omitempty"`
+	Android          []*Android `json:"android,omitempty"`
+	WindowsPhone     []*Windows `json:"windows_phone,omitempty"`
+	Windows          []*Windows `json:"windows,omitempty"`
+	WindowsUniversal []*Windows `json:"windows_universal,omitempty"`
+	Web              *Web       `json:"web,omitempty"`
 }
@@ -0,0 +1,250 @@
+package gogetter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/JustinTulloss/gogetter/wildcard"
+)
+
+// What to do with cards that don't have everything wildcard requires.
+type InvalidCardPolicy int
+
+const (
+	// Invalid cards are returned as they are. This is the default, since
+	// most of what we hand out is still useful to someone.
+	PassInvalidCards InvalidCardPolicy = iota
+	// Invalid cards are replaced by an InvalidCardError.
+	DropInvalidCards
+	// Whatever can be filled in is, and cards that still aren't valid are
+	// turned into link cards.
+	RepairInvalidCards
+)
+
+// Reads a policy from its name, which is one of pass, drop or repair.
+func ParseInvalidCardPolicy(name string) (InvalidCardPolicy, error) {
+	switch strings.ToLower(name) {
+	case "", "pass":
+		return PassInvalidCards, nil
+	case "drop":
+		return DropInvalidCards, nil
+	case "repair":
+		return RepairInvalidCards, nil
+	}
+	return PassInvalidCards, fmt.Errorf("Unknown invalid card policy %q", name)
+}
+
+// Decides what ParseTags and ScrapeTags do with cards wildcard wouldn't
+// accept.
+func (s *Scraper) SetInvalidCardPolicy(policy InvalidCardPolicy) {
+	s.invalidCardPolicy = policy
+}
+
+// Returned instead of a card that isn't valid when invalid cards are being
+// dropped.
+type InvalidCardError struct {
+	Card       wildcard.Wildcard
+	Violations []wildcard.Violation
+}
+
+func (e *InvalidCardError) Error() string {
+	problems := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		problems[i] = violation.String()
+	}
+	return fmt.Sprintf("Invalid %s card for %s: %s", e.Card.BaseCard().CardType, e.Card.BaseCard().WebUrl, strings.Join(problems, ", "))
+}
+
+// Applies the scraper's invalid card policy to card.
+func (s *Scraper) checkCard(card wildcard.Wildcard) (wildcard.Wildcard, error) {
+	switch s.invalidCardPolicy {
+	case DropInvalidCards:
+		if violations := card.Validate(); len(violations) > 0 {
+			return nil, &InvalidCardError{Card: card, Violations: violations}
+		}
+	case RepairInvalidCards:
+		card = repairCard(card)
+		pruneProvenance(card)
+		if violations := card.Validate(); len(violations) > 0 {
+			// Not even a link card can be made out of it.
+			return nil, &InvalidCardError{Card: card, Violations: violations}
+		}
+	}
+	return card, nil
+}
+
+// Fills in what wildcard requires from what else is on the card, and drops
+// optional parts that are broken. Cards that are still missing something
+// become link cards.
+func repairCard(card wildcard.Wildcard) wildcard.Wildcard {
+	if len(card.Validate()) == 0 {
+		return card
+	}
+	webUrl := card.BaseCard().WebUrl
+	if metadata := card.Metadata(); metadata != nil {
+		repairMetadata(metadata)
+	}
+	switch c := card.(type) {
+	case *wildcard.ArticleCard:
+		if c.Article != nil && c.Article.Url == "" {
+			c.Article.Url = webUrl
+		}
+	case *wildcard.VideoCard:
+		if c.Media != nil {
+			c.Media.Type = wildcard.VideoMediaType
+			// There's no stand in for a missing player. The stream url
+			// can't go in an iframe, so these become link cards below.
+			// Players are usually 16:9, and something that size is better
+			// than no player at all.
+			media := c.Media
+			width, widthErr := strconv.Atoi(media.EmbeddedUrlWidth)
+			height, heightErr := strconv.Atoi(media.EmbeddedUrlHeight)
+			switch {
+			case media.EmbeddedUrlWidth == "" && heightErr == nil:
+				media.EmbeddedUrlWidth = strconv.Itoa(height * 16 / 9)
+			case media.EmbeddedUrlWidth == "":
+				media.EmbeddedUrlWidth = "640"
+			}
+			switch {
+			case media.EmbeddedUrlHeight == "" && widthErr == nil:
+				media.EmbeddedUrlHeight = strconv.Itoa(width * 9 / 16)
+			case media.EmbeddedUrlHeight == "":
+				media.EmbeddedUrlHeight = "360"
+			}
+		}
+	case *wildcard.LinkCard:
+		if c.Target == nil {
+			c.Target = &wildcard.LinkTarget{}
+		}
+		if c.Target.Url == "" {
+			c.Target.Url = webUrl
+		}
+	case *wildcard.DocumentCard:
+		if c.Document != nil && c.Document.Url == "" {
+			c.Document.Url = webUrl
+		}
+	case *wildcard.ProductCard:
+		if c.Product != nil {
+			if c.Product.Url == "" {
+				c.Product.Url = webUrl
+			}
+			if c.Product.Rating != nil && c.Product.Rating.Value == "" {
+				c.Product.Rating = nil
+			}
+		}
+	case *wildcard.PlaceCard:
+		if place := c.Place; place != nil {
+			if place.Address != nil && place.Address.StreetAddress == "" {
+				place.Address = nil
+			}
+			if place.Location != nil && !place.HasLocation() {
+				place.Location = nil
+			}
+			if place.Rating != nil && place.Rating.Value == "" {
+				place.Rating = nil
+			}
+			if place.Hours != nil && len(place.Hours.Days) != len(place.Hours.Open) {
+				place.Hours = nil
+			}
+		}
+	}
+	if violations := card.Validate(); len(violations) > 0 {
+		if _, isLink := card.(*wildcard.LinkCard); isLink {
+			return card
+		}
+		link := toLinkCard(card)
+		addWarning(link, fmt.Sprintf("Made a link card instead of an invalid %s card: %s", card.BaseCard().CardType, violations[0]))
+		return link
+	}
+	return card
+}
+
+func repairMetadata(metadata *wildcard.GenericMetadata) {
+	if metadata.Image != nil && metadata.Image.ImageUrl == "" {
+		metadata.Image = nil
+	}
+	feeds := metadata.Feeds[:0]
+	for _, feed := range metadata.Feeds {
+		if feed.Url != "" {
+			feeds = append(feeds, feed)
+		}
+	}
+	if len(feeds) == 0 {
+		feeds = nil
+	}
+	metadata.Feeds = feeds
+}
+
+// Fields wildcard requires that a card is still worth making without. The
+// player size has a stand in in repairCard. An article with no description
+// still has its citation, authors and dates, which a link card would lose,
+// so it's left to the invalid card policy.
+var nonessentialFields = map[string]bool{
+	"media.embedded_url_width":  true,
+	"media.embedded_url_height": true,
+	"article.abstract_content":  true,
+}
+
+// What card is missing that it can't do without, out of what Validate
+// checks of the card's topic. Broken optional parts, like an image with no
+// url, don't count since the card is fine without them, and neither do
+// nonessentialFields.
+func missingEssentials(card wildcard.Wildcard) []wildcard.Violation {
+	var missing []wildcard.Violation
+	for _, violation := range card.Validate() {
+		onTopic := strings.Count(violation.Field, ".") == 1
+		if onTopic && violation.Problem == wildcard.MissingProblem && !nonessentialFields[violation.Field] {
+			missing = append(missing, violation)
+		}
+	}
+	return missing
+}
+
+// The og:type of the next simplest card to try when card is missing
+// something it needs. Videos can still be articles, everything else goes
+// straight to a link. Returns nothing for
+// link cards, there's nothing simpler.
+func fallbackCardType(card wildcard.Wildcard) string {
+	switch card.BaseCard().CardType {
+	case wildcard.LinkType:
+		return ""
+	case wildcard.VideoType:
+		return "article"
+	}
+	return "website"
+}
+
+// Pages that say they're just a website but have a video get a video card.
+// tags need their aliases resolved already.
+func upgradeCardType(tags map[string]string, ogType string) string {
+	if _, hasVideo := tags["twitter:player"]; ogType == "website" && hasVideo {
+		return "video"
+	}
+	return ogType
+}
+
+// Makes a link card out of card, keeping everything cards have in common.
+func toLinkCard(card wildcard.Wildcard) *wildcard.LinkCard {
+	base := card.BaseCard()
+	link := wildcard.NewLinkCard(base.WebUrl, base.WebUrl)
+	link.Warnings = base.Warnings
+	if metadata := card.Metadata(); metadata != nil {
+		link.Target.GenericMetadata = *metadata
+	}
+	if url := wildcard.TopicUrl(card); url != "" {
+		link.Target.Url = url
+	}
+	link.Target.Description = wildcard.TopicDescription(card)
+	if base.Provenance != nil {
+		// The fields are all under target now, and the ones link cards
+		// don't have are gone.
+		link.Provenance = make(map[string]*wildcard.Provenance, len(base.Provenance))
+		for path, provenance := range base.Provenance {
+			parts := strings.SplitN(path, ".", 2)
+			link.Provenance["target."+parts[len(parts)-1]] = provenance
+		}
+		pruneProvenance(link)
+	}
+	return link
+}
@@ -0,0 +1,297 @@
+package gogetter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JustinTulloss/gogetter/wildcard"
+)
+
+func TestCardValidate(t *testing.T) {
+	t.Parallel()
+	article := wildcard.NewArticleCard("http://example.com/a", "http://example.com/a")
+	article.Article.Image = &wildcard.ImageDetails{Width: 100}
+	expected := []wildcard.Violation{
+		{Field: "article.abstract_content", Problem: "is required"},
+		{Field: "article.image.image_url", Problem: "is required"},
+	}
+	if violations := article.Validate(); !reflect.DeepEqual(violations, expected) {
+		t.Errorf("%#v != %#v", violations, expected)
+	}
+
+	video := wildcard.NewVideoCard("http://example.com/v")
+	expected = []wildcard.Violation{
+		{Field: "media.embedded_url", Problem: "is required"},
+		{Field: "media.embedded_url_width", Problem: "is required"},
+		{Field: "media.embedded_url_height", Problem: "is required"},
+	}
+	if violations := video.Validate(); !reflect.DeepEqual(violations, expected) {
+		t.Errorf("%#v != %#v", violations, expected)
+	}
+
+	link := wildcard.NewLinkCard("http://example.com/l", "http://example.com/l")
+	if violations := link.Validate(); len(violations) != 0 {
+		t.Errorf("Unexpected violations %#v", violations)
+	}
+}
+
+func TestInvalidCardPolicies(t *testing.T) {
+	t.Parallel()
+	doc := `<html><head>
+		<meta property="og:type" content="article" />
+		<meta property="og:title" content="An article" />
+		<meta property="og:description" content="About something" />
+		<meta property="og:image:width" content="640" />
+	</head></html>`
+
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	result, err := scraper.ParseTags(strings.NewReader(doc), "http://example.com/article")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result.(*wildcard.ArticleCard); !ok {
+		t.Errorf("Invalid cards should be passed through by default, got %#v", result)
+	}
+
+	scraper.SetInvalidCardPolicy(DropInvalidCards)
+	_, err = scraper.ParseTags(strings.NewReader(doc), "http://example.com/article")
+	if _, ok := err.(*InvalidCardError); !ok {
+		t.Errorf("Expected an InvalidCardError, got %#v", err)
+	}
+
+	scraper.SetInvalidCardPolicy(RepairInvalidCards)
+	result, err = scraper.ParseTags(strings.NewReader(doc), "http://example.com/article")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &wildcard.ArticleCard{
+		Card: wildcard.Card{
+			CardType: wildcard.ArticleType,
+			WebUrl:   "http://example.com/article",
+		},
+		Article: &wildcard.Article{
+			Url:             "http://example.com/article",
+			AbstractContent: "About something",
+			GenericMetadata: wildcard.GenericMetadata{
+				Title: "An article",
+			},
+		},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("%#v != %#v", result, expected)
+	}
+}
+
+func TestRepairVideoCard(t *testing.T) {
+	t.Parallel()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	scraper.SetInvalidCardPolicy(RepairInvalidCards)
+	doc := `<html><head>
+		<meta property="og:type" content="video.other" />
+		<meta property="og:title" content="A video" />
+		<meta property="og:video:url" content="http://example.com/player" />
+		<meta property="og:video:type" content="text/html" />
+		<meta property="og:video:width" content="1280" />
+	</head></html>`
+	result, err := scraper.ParseTags(strings.NewReader(doc), "http://example.com/video")
+	if err != nil {
+		t.Fatal(err)
+	}
+	video, ok := result.(*wildcard.VideoCard)
+	if !ok {
+		t.Fatalf("Expected a video card, got %#v", result)
+	}
+	// The width we were given is kept, only the height is made up.
+	if video.Media.EmbeddedUrl != "http://example.com/player" || video.Media.EmbeddedUrlWidth != "1280" || video.Media.EmbeddedUrlHeight != "720" {
+		t.Errorf("Video wasn't repaired: %#v", video.Media)
+	}
+}
+
+func TestRepairVideoWithoutPlayer(t *testing.T) {
+	t.Parallel()
+	video := wildcard.NewVideoCard("http://example.com/video")
+	video.Media.Title = "A video"
+	video.Media.StreamUrl = "http://example.com/video.mp4"
+	result := repairCard(video)
+	link, ok := result.(*wildcard.LinkCard)
+	if !ok {
+		t.Fatalf("Expected a link card, got %#v", result)
+	}
+	if link.Target.Title != "A video" || link.Target.Url != "http://example.com/video" {
+		t.Errorf("Unexpected link %#v", link.Target)
+	}
+}
+
+func TestToLinkCardProvenance(t *testing.T) {
+	t.Parallel()
+	article := wildcard.NewArticleCard("http://example.com/a", "http://example.com/a")
+	article.Article.Title = "A paper"
+	article.Article.Citation = &wildcard.Citation{Doi: "10.1002/andp.19053221004"}
+	article.Provenance = map[string]*wildcard.Provenance{
+		"article.title":        {Tag: "citation_title"},
+		"article.citation.doi": {Tag: "citation_doi"},
+	}
+	link := toLinkCard(article)
+	expected := map[string]*wildcard.Provenance{
+		"target.title": {Tag: "citation_title"},
+	}
+	if !reflect.DeepEqual(link.Provenance, expected) {
+		t.Errorf("%#v != %#v", link.Provenance, expected)
+	}
+}
+
+func TestResolveCardType(t *testing.T) {
+	t.Parallel()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	noVideo := `<html><head>
+		<meta property="og:type" content="video.other" />
+		<meta property="og:title" content="A video" />
+		<meta property="og:description" content="Not actually a video" />
+	</head></html>`
+	result, err := scraper.ParseTags(strings.NewReader(noVideo), "http://example.com/video")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &wildcard.ArticleCard{
+		Card: wildcard.Card{
+			CardType: wildcard.ArticleType,
+			WebUrl:   "http://example.com/video",
+			Warnings: []string{"Fell back from video to article card: media.embedded_url is required"},
+		},
+		Article: &wildcard.Article{
+			Url:             "http://example.com/video",
+			AbstractContent: "Not actually a video",
+			GenericMetadata: wildcard.GenericMetadata{
+				Title: "A video",
+			},
+		},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("%#v != %#v", result, expected)
+	}
+
+	website := `<html><head>
+		<meta property="og:title" content="A video" />
+		<meta property="og:video" content="http://example.com/player" />
+		<meta property="og:video:type" content="text/html" />
+		<meta property="og:video:width" content="1280" />
+		<meta property="og:video:height" content="720" />
+	</head></html>`
+	result, err = scraper.ParseTags(strings.NewReader(website), "http://example.com/video")
+	if err != nil {
+		t.Fatal(err)
+	}
+	video, ok := result.(*wildcard.VideoCard)
+	if !ok {
+		t.Fatalf("Expected a video card, got %#v", result)
+	}
+	if violations := video.Validate(); len(violations) != 0 {
+		t.Errorf("Unexpected violations %#v", violations)
+	}
+}
+
+func TestArticleWithoutDescription(t *testing.T) {
+	t.Parallel()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	article := `<html><head>
+		<meta property="og:type" content="article" />
+		<meta property="og:title" content="An article" />
+		<meta property="og:image" content="http://example.com/a.jpg" />
+		<meta name="citation_publication_date" content="2015/03/04" />
+	</head></html>`
+	published := timePtr(time.Date(2015, 3, 4, 0, 0, 0, 0, time.UTC))
+
+	// A description isn't worth losing the rest of the article over, so
+	// what happens to it is up to the invalid card policy.
+	result, err := scraper.ParseTags(strings.NewReader(article), "http://example.com/article")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &wildcard.ArticleCard{
+		Card: wildcard.Card{
+			CardType: wildcard.ArticleType,
+			WebUrl:   "http://example.com/article",
+		},
+		Article: &wildcard.Article{
+			Url: "http://example.com/article",
+			GenericMetadata: wildcard.GenericMetadata{
+				Title:           "An article",
+				Image:           &wildcard.ImageDetails{ImageUrl: "http://example.com/a.jpg"},
+				PublicationDate: published,
+			},
+		},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("%#v != %#v", result, expected)
+	}
+
+	scraper.SetInvalidCardPolicy(RepairInvalidCards)
+	result, err = scraper.ParseTags(strings.NewReader(article), "http://example.com/article")
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, ok := result.(*wildcard.LinkCard)
+	if !ok {
+		t.Fatalf("Expected a link card, got %#v", result)
+	}
+	if date := link.Target.PublicationDate; date == nil || !date.Equal(*published) {
+		t.Errorf("Publication date was %v", date)
+	}
+	if violations := result.Validate(); len(violations) != 0 {
+		t.Errorf("Unexpected violations %#v", violations)
+	}
+}
+
+func TestProductFallback(t *testing.T) {
+	t.Parallel()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	// A broken rating is something repairCard can drop, it doesn't make the
+	// page any less of a product.
+	brokenRating := `<html><head>
+		<meta property="og:type" content="product" />
+		<meta property="og:title" content="Widget" />
+		<meta property="rating:count" content="12" />
+	</head></html>`
+	result, err := scraper.ParseTags(strings.NewReader(brokenRating), "http://example.com/widget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result.(*wildcard.ProductCard); !ok || len(result.BaseCard().Warnings) != 0 {
+		t.Errorf("Expected a product card without warnings, got %#v", result)
+	}
+
+	// Without a url a link card would be just as broken, so the product
+	// card stays.
+	noUrl := `<html><head>
+		<meta property="og:type" content="product" />
+		<meta property="og:title" content="Widget" />
+	</head></html>`
+	result, err = scraper.ParseTags(strings.NewReader(noUrl), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result.(*wildcard.ProductCard); !ok {
+		t.Errorf("Expected a product card, got %#v", result)
+	}
+	missing := missingEssentials(result)
+	if len(missing) != 1 || missing[0].Field != "product.url" {
+		t.Errorf("Expected product.url to be missing, got %#v", missing)
+	}
+}
@@ -0,0 +1,76 @@
+package gogetter
+
+import (
+	"html"
+	"strings"
+
+	"github.com/JustinTulloss/gogetter/wildcard"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Reads the Highwire Press citation_* tags that journals, PubMed, arXiv and
+// friends put on article pages. They're kept under their own names, which
+// the schema and aliases know about.
+func extractCitation(page *Page) error {
+	page.Doc.Find(`meta[name^="citation_"][content]`).Each(func(i int, selection *goquery.Selection) {
+		name, _ := selection.Attr("name")
+		name = strings.ToLower(strings.TrimSpace(name))
+		content, _ := selection.Attr("content")
+		content = strings.TrimSpace(html.UnescapeString(content))
+		if content == "" {
+			return
+		}
+		switch name {
+		case "citation_doi":
+			content = normalizeDoi(content)
+		case "citation_pdf_url":
+			resolved, ok := resolveUrl(page.Url, content)
+			if !ok {
+				return
+			}
+			content = resolved
+		}
+		page.AddTag(name, content)
+	})
+	return nil
+}
+
+// DOIs show up bare, as doi:10.1000/xyz or as a doi.org link.
+func normalizeDoi(doi string) string {
+	lower := strings.ToLower(doi)
+	for _, prefix := range []string{"https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "http://dx.doi.org/", "doi:"} {
+		if strings.HasPrefix(lower, prefix) {
+			return strings.TrimSpace(doi[len(prefix):])
+		}
+	}
+	return doi
+}
+
+// There's a citation_author tag for every author, in order, which doesn't
+// fit in the tags. The byline is made out of them if there isn't one.
+func extractCitationAuthors(page *Page, card wildcard.Wildcard) error {
+	articleCard, ok := card.(*wildcard.ArticleCard)
+	if !ok || articleCard.Article == nil {
+		return nil
+	}
+	var authors []string
+	page.Doc.Find(`meta[name="citation_author"][content]`).Each(func(i int, selection *goquery.Selection) {
+		author, _ := selection.Attr("content")
+		author = strings.TrimSpace(html.UnescapeString(author))
+		if author != "" {
+			authors = append(authors, author)
+		}
+	})
+	if len(authors) == 0 {
+		return nil
+	}
+	article := articleCard.Article
+	if article.Citation == nil {
+		article.Citation = &wildcard.Citation{}
+	}
+	article.Citation.Authors = authors
+	if article.Byline == "" {
+		article.Byline = strings.Join(authors, "; ")
+	}
+	return nil
+}
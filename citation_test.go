@@ -0,0 +1,59 @@
+package gogetter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JustinTulloss/gogetter/wildcard"
+)
+
+func TestCitation(t *testing.T) {
+	t.Parallel()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	doc := `<html>
+		<head>
+			<meta property="og:type" content="website" />
+			<meta name="citation_title" content="On the Electrodynamics of Moving Bodies" />
+			<meta name="citation_author" content="Einstein, Albert" />
+			<meta name="citation_author" content="Someone, Else" />
+			<meta name="citation_publication_date" content="1905/06/30" />
+			<meta name="citation_journal_title" content="Annalen der Physik" />
+			<meta name="citation_volume" content="17" />
+			<meta name="citation_doi" content="https://doi.org/10.1002/andp.19053221004" />
+			<meta name="citation_pdf_url" content="/pdf/andp.19053221004.pdf" />
+		</head>
+	</html>`
+	expected := &wildcard.ArticleCard{
+		Card: wildcard.Card{
+			CardType: wildcard.ArticleType,
+			WebUrl:   "http://journal.example.com/abs/1905",
+		},
+		Article: &wildcard.Article{
+			Url:    "http://journal.example.com/abs/1905",
+			Byline: "Einstein, Albert; Someone, Else",
+			Citation: &wildcard.Citation{
+				Doi:     "10.1002/andp.19053221004",
+				Journal: "Annalen der Physik",
+				Volume:  "17",
+				Authors: []string{"Einstein, Albert", "Someone, Else"},
+				PdfUrl:  "http://journal.example.com/pdf/andp.19053221004.pdf",
+			},
+			GenericMetadata: wildcard.GenericMetadata{
+				Title:           "On the Electrodynamics of Moving Bodies",
+				PublicationDate: timePtr(time.Date(1905, 6, 30, 0, 0, 0, 0, time.UTC)),
+			},
+		},
+	}
+	result, err := scraper.ParseTags(strings.NewReader(doc), "http://journal.example.com/abs/1905")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("%#v != %#v", result, expected)
+	}
+}
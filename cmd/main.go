@@ -1,18 +1,29 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"net/http"
 	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/JustinTulloss/gogetter"
+	"github.com/JustinTulloss/gogetter/imageproxy"
+	"github.com/JustinTulloss/gogetter/renderer"
+	"github.com/JustinTulloss/gogetter/wildcard"
 	"github.com/JustinTulloss/hut"
 )
 
 var service *hut.Service
 var scraper *gogetter.Scraper
+var cardRenderer *renderer.Renderer
 
 func handler(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
@@ -24,7 +35,29 @@ func handler(w http.ResponseWriter, r *http.Request) {
 		service.ErrorReply(err, w)
 		return
 	}
-	tags, httpErr := scraper.ScrapeTags(decodedUrl)
+	if validate, _ := strconv.ParseBool(r.Form.Get("validate")); validate {
+		report, err := scraper.Validate(r.Context(), decodedUrl)
+		if err != nil {
+			service.HttpErrorReply(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		service.Reply(report, w)
+		return
+	}
+	if raw, _ := strconv.ParseBool(r.Form.Get("raw")); raw {
+		rawTags, err := scraper.ScrapeRawTags(decodedUrl)
+		if err != nil {
+			service.HttpErrorReply(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		service.Reply(rawTags, w)
+		return
+	}
+	requestScraper := scraper
+	if debug, _ := strconv.ParseBool(r.Form.Get("debug")); debug {
+		requestScraper = scraper.WithProvenance()
+	}
+	tags, httpErr := requestScraper.ScrapeTags(decodedUrl)
 	if httpErr != nil {
 		service.HttpErrorReply(w, httpErr.Error(), http.StatusInternalServerError)
 		return
@@ -32,21 +65,110 @@ func handler(w http.ResponseWriter, r *http.Request) {
 	service.Reply(tags, w)
 }
 
+// The rendered page only needs styles and images, plus the player for
+// video cards, so nothing else is allowed to load.
+const renderPolicy = "default-src 'none'; img-src http: https: data:; style-src 'unsafe-inline'; frame-src http: https:; base-uri 'none'; form-action 'none'"
+
+func renderHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		service.ErrorReply(err, w)
+		return
+	}
+	decodedUrl, err := url.QueryUnescape(r.Form.Get("url"))
+	if err != nil {
+		service.ErrorReply(err, w)
+		return
+	}
+	result, err := scraper.ScrapeTags(decodedUrl)
+	if err != nil {
+		service.HttpErrorReply(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	card, ok := result.(wildcard.Wildcard)
+	if !ok {
+		service.HttpErrorReply(w, fmt.Sprintf("Can't render %s", decodedUrl), http.StatusInternalServerError)
+		return
+	}
+	var page bytes.Buffer
+	if err := cardRenderer.RenderPage(&page, card); err != nil {
+		service.HttpErrorReply(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", renderPolicy)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Write(page.Bytes())
+}
+
+// Lets the rules file be edited without restarting the service.
+func reloadRulesOnHangup() {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	for range hangups {
+		if err := scraper.ReloadRules(); err != nil {
+			service.Log.Error("Could not reload rules, keeping the old ones", "err", err)
+		} else {
+			service.Log.Info("Reloaded rules")
+		}
+	}
+}
+
 func main() {
 	var err error
 	service = hut.NewService(nil)
 	service.Router.HandleFunc("/", handler)
+	service.Router.HandleFunc("/render", renderHandler)
 	scraper, err = gogetter.NewScraper("", service.Env.GetBool("check_robots_txt"))
 	if err != nil {
 		service.Log.Fatal("Could not create a scraper", "err", err)
 	}
+	cardRenderer, err = renderer.New()
+	if err != nil {
+		service.Log.Fatal("Could not create a renderer", "err", err)
+	}
+	scraper.SetProbeImages(service.Env.GetBool("probe_images"))
+	scraper.SetIncludeImageLocation(service.Env.GetBool("include_image_location"))
+	invalidCardPolicy, err := gogetter.ParseInvalidCardPolicy(service.Env.GetString("invalid_cards"))
+	if err != nil {
+		service.Log.Fatal("Could not set the invalid card policy", "err", err)
+	}
+	scraper.SetInvalidCardPolicy(invalidCardPolicy)
+	if key := service.Env.GetString("image_proxy_key"); key != "" {
+		proxy, err := imageproxy.New(
+			service.Env.GetString("image_proxy_url"),
+			[]byte(key),
+			service.Env.GetString("image_proxy_cache"),
+			gogetter.NewSafeClient(time.Minute),
+		)
+		if err != nil {
+			service.Log.Fatal("Could not create the image proxy, set image_proxy_url", "err", err)
+		}
+		proxy.SetUserAgent(gogetter.DEFAULT_UA)
+		service.Router.Handle("/image", proxy)
+		if service.Env.GetBool("proxy_card_images") {
+			scraper.SetImageUrlRewriter(proxy)
+		}
+	}
+	if rulesFile := service.Env.GetString("rules_file"); rulesFile != "" {
+		if err := scraper.LoadRules(rulesFile); err != nil {
+			service.Log.Fatal("Could not load rules", "err", err)
+		}
+		go reloadRulesOnHangup()
+	}
 
+	validate := flag.Bool("validate", false, "Report problems with the page's tags instead of scraping it")
 	flag.Parse()
 	protocol := service.Env.GetString("protocol")
 	if protocol == "http" {
 		service.Start()
 	} else if len(flag.Args()) != 0 {
-		tags, err := scraper.ScrapeTags(flag.Arg(0))
+		var tags interface{}
+		if *validate {
+			tags, err = scraper.Validate(context.Background(), flag.Arg(0))
+		} else {
+			tags, err = scraper.ScrapeTags(flag.Arg(0))
+		}
 		if err != nil {
 			fmt.Printf("Could not fetch: %s\n", err)
 			return
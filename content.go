@@ -0,0 +1,181 @@
+package gogetter
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// How long an excerpt of the main content can get, in characters.
+const maxExcerptLength = 300
+
+// A leisurely adult reading speed, used to estimate reading time.
+const wordsPerMinute = 200
+
+// Paragraphs shorter than this are usually bylines, captions or buttons.
+const minParagraphLength = 25
+
+// These elements never contain anything we'd want in an excerpt.
+const unlikelyContent = "script, style, noscript, nav, header, footer, aside, form, iframe, svg, button, select, textarea"
+
+var (
+	positiveClassNames = regexp.MustCompile(`(?i)article|body|content|entry|hentry|main|page|post|text|blog|story`)
+	negativeClassNames = regexp.MustCompile(`(?i)comment|meta|footer|footnote|foot|masthead|sidebar|sponsor|\bads?\b|ad-break|advert|promo|related|share|social|nav|menu|header|widget|popup|cookie|banner|combx|scroll|shoutbox`)
+	whitespace         = regexp.MustCompile(`\s+`)
+)
+
+// What we found when looking for the main content of a page.
+type pageContent struct {
+	Excerpt     string
+	WordCount   int
+	ReadingTime int
+}
+
+// Finds the main body of the page in the same spirit as Arc90's readability:
+// paragraphs award points to their parents and grandparents, containers get
+// a nudge based on their class and id, and the whole thing is discounted by
+// how much of it is links. Returns nil if there doesn't seem to be any real
+// content on the page.
+func extractContent(doc *goquery.Document) *pageContent {
+	body := doc.Find("body").Clone()
+	body.Find(unlikelyContent).Remove()
+	body.Find("*").Each(func(i int, selection *goquery.Selection) {
+		if isUnlikelyCandidate(selection) {
+			selection.Remove()
+		}
+	})
+
+	scores := make(map[*html.Node]float64)
+	var candidates []*goquery.Selection
+	addScore := func(selection *goquery.Selection, score float64) {
+		if selection.Length() == 0 {
+			return
+		}
+		node := selection.Get(0)
+		if _, ok := scores[node]; !ok {
+			scores[node] = initialScore(selection)
+			candidates = append(candidates, selection)
+		}
+		scores[node] += score
+	}
+	body.Find("p, pre, td, blockquote").Each(func(i int, paragraph *goquery.Selection) {
+		text := normalizeWhitespace(paragraph.Text())
+		length := utf8.RuneCountInString(text)
+		if length < minParagraphLength {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(length)/100, 3)
+		addScore(paragraph.Parent(), score)
+		addScore(paragraph.Parent().Parent(), score/2)
+	})
+
+	var best *goquery.Selection
+	bestScore := 0.0
+	for _, candidate := range candidates {
+		score := scores[candidate.Get(0)] * (1 - linkDensity(candidate))
+		if score > bestScore {
+			best = candidate
+			bestScore = score
+		}
+	}
+	if best == nil {
+		return nil
+	}
+
+	var paragraphs []string
+	best.Find("p, pre, blockquote, li, h2, h3").Each(func(i int, selection *goquery.Selection) {
+		text := normalizeWhitespace(selection.Text())
+		if text != "" && selection.ParentsFiltered("p, pre, blockquote, li").Length() == 0 {
+			paragraphs = append(paragraphs, text)
+		}
+	})
+	text := strings.Join(paragraphs, " ")
+	if text == "" {
+		text = normalizeWhitespace(best.Text())
+	}
+	words := len(strings.Fields(text))
+	if words == 0 {
+		return nil
+	}
+	return &pageContent{
+		Excerpt:     truncateText(text, maxExcerptLength),
+		WordCount:   words,
+		ReadingTime: int(math.Ceil(float64(words) / wordsPerMinute)),
+	}
+}
+
+// Elements whose class or id make them look like page furniture, unless
+// something about them also looks like the main content.
+func isUnlikelyCandidate(selection *goquery.Selection) bool {
+	if goquery.NodeName(selection) == "body" || selection.Is("article, main") {
+		return false
+	}
+	class, _ := selection.Attr("class")
+	id, _ := selection.Attr("id")
+	names := class + " " + id
+	return negativeClassNames.MatchString(names) && !positiveClassNames.MatchString(names)
+}
+
+func initialScore(selection *goquery.Selection) float64 {
+	var score float64
+	switch goquery.NodeName(selection) {
+	case "article", "main":
+		score = 10
+	case "div":
+		score = 5
+	case "pre", "td", "blockquote", "section":
+		score = 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score = -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score = -5
+	}
+	for _, attr := range []string{"class", "id"} {
+		value, ok := selection.Attr(attr)
+		if !ok {
+			continue
+		}
+		if negativeClassNames.MatchString(value) {
+			score -= 25
+		}
+		if positiveClassNames.MatchString(value) {
+			score += 25
+		}
+	}
+	return score
+}
+
+// The fraction of the text in selection that is inside links.
+func linkDensity(selection *goquery.Selection) float64 {
+	length := utf8.RuneCountInString(normalizeWhitespace(selection.Text()))
+	if length == 0 {
+		return 0
+	}
+	linkLength := 0
+	selection.Find("a").Each(func(i int, link *goquery.Selection) {
+		linkLength += utf8.RuneCountInString(normalizeWhitespace(link.Text()))
+	})
+	return float64(linkLength) / float64(length)
+}
+
+func normalizeWhitespace(text string) string {
+	return strings.TrimSpace(whitespace.ReplaceAllString(text, " "))
+}
+
+// Shortens text to at most max characters, breaking on a word boundary if
+// there is one nearby.
+func truncateText(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	runes := []rune(text)
+	cut := string(runes[:max-1])
+	if space := strings.LastIndex(cut, " "); space > len(cut)/2 {
+		cut = cut[:space]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
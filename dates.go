@@ -0,0 +1,134 @@
+package gogetter
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layouts we've seen publishers use for dates. They're tried in order, so
+// the more specific ones need to come first.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04:05.999999999-0700",
+	"2006-01-02T15:04:05 -0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04-0700",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 -0700 MST",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+	"20060102",
+	// EXIF uses colons in the date too.
+	"2006:01:02 15:04:05-07:00",
+	"2006:01:02 15:04:05",
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC850,
+	time.UnixDate,
+	time.RubyDate,
+	time.ANSIC,
+	"January 2, 2006 3:04 PM MST",
+	"January 2, 2006 3:04 PM",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"2006-01",
+	"2006",
+}
+
+// Parses a date in any of the formats we know about. Unix timestamps in
+// seconds or milliseconds are accepted too.
+func parseDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	if t, ok := parseTimestamp(value); ok {
+		return t, true
+	}
+	for _, layout := range dateLayouts {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t, true
+		}
+	}
+	// Some people tack "UTC" or "GMT" onto an ISO 8601 timestamp where a
+	// "Z" belongs.
+	for _, zone := range []string{" UTC", " GMT"} {
+		if strings.HasSuffix(value, zone) {
+			return parseDate(strings.TrimSuffix(value, zone) + "Z")
+		}
+	}
+	return time.Time{}, false
+}
+
+// Timestamps are only recognized in the range where they're plausible for
+// something published on the web, so that things like "20150304" are
+// treated as dates rather than as a moment in 1970.
+func parseTimestamp(value string) (time.Time, bool) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	switch len(value) {
+	case 10:
+		return time.Unix(n, 0).UTC(), true
+	case 13:
+		return time.Unix(n/1000, (n%1000)*int64(time.Millisecond)).UTC(), true
+	}
+	return time.Time{}, false
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// A mapstructure decode hook that turns strings into times. Dates we can't
+// make sense of fail the field, so they show up in the card's warnings
+// rather than disappearing.
+func decodeDateHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if to != timeType {
+		return data, nil
+	}
+	var value string
+	var t time.Time
+	var ok bool
+	switch from.Kind() {
+	case reflect.String:
+		value = data.(string)
+		if strings.TrimSpace(value) == "" {
+			return time.Time{}, nil
+		}
+		t, ok = parseDate(value)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		value = strconv.FormatInt(reflect.ValueOf(data).Int(), 10)
+		t, ok = parseTimestamp(value)
+	case reflect.Float64:
+		value = strconv.FormatInt(int64(data.(float64)), 10)
+		t, ok = parseTimestamp(value)
+	default:
+		return data, nil
+	}
+	if !ok {
+		return nil, fmt.Errorf("%q is not a date we understand", value)
+	}
+	return t, nil
+}
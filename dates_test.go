@@ -0,0 +1,43 @@
+package gogetter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	t.Parallel()
+	pst := time.FixedZone("", -8*60*60)
+	cases := []struct {
+		in  string
+		out time.Time
+	}{
+		{"2015-03-04", time.Date(2015, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{"2015-03-04T10:00:00-0800", time.Date(2015, 3, 4, 10, 0, 0, 0, pst)},
+		{"2015-03-04T10:00:00-08:00", time.Date(2015, 3, 4, 10, 0, 0, 0, pst)},
+		{"2015-03-04T18:00:00Z", time.Date(2015, 3, 4, 18, 0, 0, 0, time.UTC)},
+		{"2015-03-04T18:00:00 UTC", time.Date(2015, 3, 4, 18, 0, 0, 0, time.UTC)},
+		{"2015-03-04 18:00:00", time.Date(2015, 3, 4, 18, 0, 0, 0, time.UTC)},
+		{"Wed, 04 Mar 2015 18:00:00 GMT", time.Date(2015, 3, 4, 18, 0, 0, 0, time.UTC)},
+		{"Wed, 4 Mar 2015 10:00:00 -0800", time.Date(2015, 3, 4, 10, 0, 0, 0, pst)},
+		{"March 4, 2015", time.Date(2015, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{"1425492000", time.Date(2015, 3, 4, 18, 0, 0, 0, time.UTC)},
+		{"1425492000000", time.Date(2015, 3, 4, 18, 0, 0, 0, time.UTC)},
+		{"20150304", time.Date(2015, 3, 4, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		parsed, ok := parseDate(c.in)
+		if !ok {
+			t.Errorf("Could not parse %q", c.in)
+			continue
+		}
+		if !parsed.Equal(c.out) {
+			t.Errorf("%q parsed as %s, expected %s", c.in, parsed, c.out)
+		}
+	}
+	for _, garbage := range []string{"", "yesterday", "not a date"} {
+		if _, ok := parseDate(garbage); ok {
+			t.Errorf("Expected %q not to parse", garbage)
+		}
+	}
+}
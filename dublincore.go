@@ -0,0 +1,53 @@
+package gogetter
+
+import (
+	"html"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Dublin Core shows up as DC.title, dc.date.issued, DCTERMS.created and so
+// on in meta names, or as dc:title and dcterms:created in RDFa properties.
+// We lowercase them and turn the first dot into a colon so they look like
+// the rest of our tags. Subjects can be listed more than once, so they're
+// all kept, separated by semicolons.
+func extractDublinCore(page *Page) error {
+	page.Doc.Find("meta[content]").Each(func(i int, selection *goquery.Selection) {
+		name, ok := selection.Attr("name")
+		if !ok {
+			name, _ = selection.Attr("property")
+		}
+		key := dublinCoreKey(page.TagName(name))
+		if key == "" {
+			return
+		}
+		content, _ := selection.Attr("content")
+		content = strings.TrimSpace(html.UnescapeString(content))
+		addDublinCoreTag(page, key, content)
+	})
+	return nil
+}
+
+// Turns a meta name or property into the tag we keep it under, or "" if it
+// isn't Dublin Core.
+func dublinCoreKey(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, prefix := range []string{"dc", "dcterms"} {
+		if strings.HasPrefix(name, prefix+".") || strings.HasPrefix(name, prefix+":") {
+			return prefix + ":" + name[len(prefix)+1:]
+		}
+	}
+	return ""
+}
+
+func addDublinCoreTag(page *Page, key, value string) {
+	if value == "" {
+		return
+	}
+	if strings.HasSuffix(key, ":subject") {
+		page.appendTag(key, value)
+		return
+	}
+	page.AddTag(key, value)
+}
@@ -0,0 +1,362 @@
+package gogetter
+
+import (
+	"fmt"
+	"html"
+	"mime"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/JustinTulloss/gogetter/applink"
+	"github.com/JustinTulloss/gogetter/wildcard"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// The page extractors are working on, and what's been found on it so far.
+type Page struct {
+	Doc *goquery.Document
+	// Where the page came from, can be empty.
+	Url string
+
+	// Everything the extractors have found so far, which is later decoded
+	// into a card. Open graph defers to the first tag it understands, so
+	// once a tag is found it stays. Extractors add tags with AddTag and
+	// read them with Tag.
+	tags map[string]string
+	// Every value found for each tag, including the ones that lost.
+	rawTags map[string][]string
+	// The prefixes we read tags for, including any the page declares itself.
+	prefixes []string
+	// What the page calls prefixes we know by another name.
+	renamedPrefixes map[string]string
+	aliases         map[string][]string
+}
+
+// The value tag will go on the card with, if it's been found.
+func (p *Page) Tag(tag string) (string, bool) {
+	value, ok := p.tags[tag]
+	return value, ok
+}
+
+// Whether tag or any of its aliases has been found.
+func (p *Page) HasTag(tag string) bool {
+	return hasTag(p.tags, p.aliases, tag)
+}
+
+// Adds a tag unless it's already been found. Every value is kept for the raw
+// tags either way, so pages that repeat a tag don't lose anything there.
+func (p *Page) AddTag(tag, value string) {
+	if p.rawTags == nil {
+		p.rawTags = make(map[string][]string)
+	}
+	p.rawTags[tag] = append(p.rawTags[tag], value)
+	if _, alreadySet := p.tags[tag]; !alreadySet {
+		p.tags[tag] = value
+	}
+}
+
+// Adds value to a tag that holds a list, like keywords, separated by
+// semicolons. Values that are already in the list aren't added again.
+func (p *Page) appendTag(tag, value string) {
+	existing, alreadySet := p.tags[tag]
+	if !alreadySet {
+		p.AddTag(tag, value)
+		return
+	}
+	for _, item := range splitKeywords(existing) {
+		if item == value {
+			return
+		}
+	}
+	p.rawTags[tag] = append(p.rawTags[tag], value)
+	p.tags[tag] = existing + "; " + value
+}
+
+// Every value found for every tag. The first value is always the one that
+// goes on the card, and values aren't repeated.
+func (p *Page) RawTags() map[string][]string {
+	raw := make(map[string][]string, len(p.tags))
+	for tag, value := range p.tags {
+		values := []string{value}
+		seen := map[string]bool{value: true}
+		for _, other := range p.rawTags[tag] {
+			if !seen[other] {
+				seen[other] = true
+				values = append(values, other)
+			}
+		}
+		raw[tag] = values
+	}
+	return raw
+}
+
+// The prefixes of the meta tags we read, like og and twitter.
+func (p *Page) Prefixes() []string {
+	return p.prefixes
+}
+
+// Turns a tag from the page into the name we know it by, in case the page
+// declared its own prefix for a namespace we know.
+func (p *Page) TagName(name string) string {
+	parts := strings.SplitN(name, ":", 2)
+	if len(parts) == 2 {
+		if prefix, ok := p.renamedPrefixes[parts[0]]; ok {
+			return prefix + ":" + parts[1]
+		}
+	}
+	return name
+}
+
+// An Extractor pulls metadata out of a parsed page and adds it to the page's
+// tags. Tags only count if something maps them onto the card, either an
+// `ogtag` on the schema or an alias of one.
+type Extractor interface {
+	Extract(page *Page) error
+}
+
+// A CardExtractor fills in parts of a card that don't fit in a flat list of
+// tags. It runs after the card has been built from the tags.
+type CardExtractor interface {
+	ExtractCard(page *Page, card wildcard.Wildcard) error
+}
+
+// Lets an ordinary function be used as an Extractor.
+type ExtractorFunc func(page *Page) error
+
+func (f ExtractorFunc) Extract(page *Page) error {
+	return f(page)
+}
+
+// Lets an ordinary function be used as a CardExtractor.
+type CardExtractorFunc func(page *Page, card wildcard.Wildcard) error
+
+func (f CardExtractorFunc) ExtractCard(page *Page, card wildcard.Wildcard) error {
+	return f(page, card)
+}
+
+// The built in extractors. Order matters, since the first one to find a tag
+// wins and the fallbacks at the end only kick in when nothing better was
+// found.
+var (
+	TitleExtractor         = ExtractorFunc(extractTitle)
+	FaviconExtractor       = ExtractorFunc(extractFavicon)
+	MetaTagExtractor       = ExtractorFunc(extractMetaTags)
+	CitationExtractor      = ExtractorFunc(extractCitation)
+	DublinCoreExtractor    = ExtractorFunc(extractDublinCore)
+	JSONLDExtractor        = ExtractorFunc(extractJSONLD)
+	MicrodataExtractor     = ExtractorFunc(extractMicrodata)
+	RDFaExtractor          = ExtractorFunc(extractRDFa)
+	TimeElementExtractor   = ExtractorFunc(extractTimeElement)
+	FallbackImageExtractor = ExtractorFunc(extractFallbackImage)
+	VideoPlayerExtractor   = ExtractorFunc(extractVideoPlayer)
+	ContentExtractor       = ExtractorFunc(extractPageContent)
+
+	FeedExtractor            = CardExtractorFunc(extractFeeds)
+	AppLinkExtractor         = CardExtractorFunc(extractAppLinks)
+	CitationAuthorsExtractor = CardExtractorFunc(extractCitationAuthors)
+)
+
+// The extractors every new Scraper starts out with.
+func DefaultExtractors() []Extractor {
+	return []Extractor{
+		TitleExtractor,
+		FaviconExtractor,
+		MetaTagExtractor,
+		CitationExtractor,
+		DublinCoreExtractor,
+		JSONLDExtractor,
+		MicrodataExtractor,
+		RDFaExtractor,
+		TimeElementExtractor,
+		FallbackImageExtractor,
+		VideoPlayerExtractor,
+		ContentExtractor,
+	}
+}
+
+// The card extractors every new Scraper starts out with.
+func DefaultCardExtractors() []CardExtractor {
+	return []CardExtractor{
+		FeedExtractor,
+		AppLinkExtractor,
+		CitationAuthorsExtractor,
+	}
+}
+
+func extractTitle(page *Page) error {
+	if title := page.Doc.Find("title").Text(); title != "" {
+		page.AddTag("title", html.UnescapeString(title))
+	}
+	return nil
+}
+
+func extractFavicon(page *Page) error {
+	if _, alreadySet := page.Tag("favicon"); alreadySet {
+		return nil
+	}
+	favicon, ok := page.Doc.Find("link[rel~=icon]").Attr("href")
+	if !ok {
+		return nil
+	}
+	faviconUrl, err := url.Parse(favicon)
+	if err != nil {
+		return nil
+	}
+	if faviconUrl.Scheme == "" {
+		faviconUrl.Scheme = "http"
+	}
+	if faviconUrl.Host == "" {
+		u, _ := url.Parse(page.Url)
+		faviconUrl.Host = u.Host
+	}
+	page.AddTag("favicon", faviconUrl.String())
+	return nil
+}
+
+// Finds all meta tags for all the prefixes the page uses that we support,
+// plus a few plain ones.
+func extractMetaTags(page *Page) error {
+	metaTags := page.Doc.Find(`meta[name="description"]`)
+	for _, metaTag := range rawMetaTags {
+		metaTags = metaTags.Add(fmt.Sprintf(`meta[name="%s"]`, metaTag))
+	}
+	for _, prefix := range page.Prefixes() {
+		metaTags = metaTags.Add(fmt.Sprintf(`meta[property^="%s:"]`, prefix))
+		metaTags = metaTags.Add(fmt.Sprintf(`meta[name^="%s:"]`, prefix))
+	}
+	metaTags.Each(func(i int, selection *goquery.Selection) {
+		key, ok := selection.Attr("name")
+		if !ok {
+			key, _ = selection.Attr("property")
+		}
+		key = page.TagName(key)
+		content, _ := selection.Attr("content")
+		// Open graph defers to the first tag that we understand.
+		page.AddTag(key, html.UnescapeString(content))
+	})
+	return nil
+}
+
+// Open graph videos are usually files, which can't go in an iframe, so they
+// only stand in for a player when the page says they're a web page. The
+// https url wins when there's both.
+func extractVideoPlayer(page *Page) error {
+	videoType, _ := page.Tag("og:video:type")
+	if mediaType, _, err := mime.ParseMediaType(videoType); err != nil || mediaType != "text/html" {
+		return nil
+	}
+	for _, tag := range []string{"og:video:secure_url", "og:video:url", "og:video"} {
+		if player, ok := page.Tag(tag); ok {
+			page.AddTag("video:player", player)
+			return nil
+		}
+	}
+	return nil
+}
+
+// A <time> element is a last resort for finding out when something was
+// published, so prefer one that says it's the publication date.
+func extractTimeElement(page *Page) error {
+	if _, alreadySet := page.Tag("time:datetime"); alreadySet {
+		return nil
+	}
+	timeTag := page.Doc.Find(`time[pubdate][datetime], time[itemprop="datePublished"][datetime]`)
+	if timeTag.Length() == 0 {
+		timeTag = page.Doc.Find("time[datetime]")
+	}
+	if datetime, ok := timeTag.First().Attr("datetime"); ok {
+		page.AddTag("time:datetime", datetime)
+	}
+	return nil
+}
+
+func extractFallbackImage(page *Page) error {
+	if page.HasTag("og:image") {
+		return nil
+	}
+	image := findFallbackImage(page.Doc, page.Url)
+	if image == nil {
+		return nil
+	}
+	page.AddTag("image:fallback", image.Url)
+	if image.Width > 0 {
+		page.AddTag("image:fallback:width", strconv.Itoa(image.Width))
+	}
+	if image.Height > 0 {
+		page.AddTag("image:fallback:height", strconv.Itoa(image.Height))
+	}
+	return nil
+}
+
+// Digging the description out of the page is a lot of work and not nearly as
+// good as what the publisher tells us, so only do it when they haven't told
+// us anything.
+func extractPageContent(page *Page) error {
+	if page.HasTag("og:description") {
+		return nil
+	}
+	content := extractContent(page.Doc)
+	if content == nil {
+		return nil
+	}
+	page.AddTag("content:excerpt", content.Excerpt)
+	page.AddTag("content:word_count", strconv.Itoa(content.WordCount))
+	page.AddTag("content:reading_time", strconv.Itoa(content.ReadingTime))
+	return nil
+}
+
+func extractFeeds(page *Page, card wildcard.Wildcard) error {
+	card.Metadata().Feeds = discoverFeeds(page.Doc, page.Url)
+	return nil
+}
+
+// Twitter's app card platforms and the App Links platforms they stand in for.
+var twitterAppPlatforms = [][2]string{
+	{"iphone", "iphone"},
+	{"ipad", "ipad"},
+	{"googleplay", "android"},
+}
+
+// App links can list several apps for a platform, so unlike everything else
+// they're read in document order rather than out of the tag map. Twitter's
+// app card tags fill in for any platform the page has no al: tags for.
+func extractAppLinks(page *Page, card wildcard.Wildcard) error {
+	var properties []applink.Property
+	platforms := make(map[string]bool)
+	page.Doc.Find(`meta[property^="al:"], meta[name^="al:"]`).Each(func(i int, selection *goquery.Selection) {
+		key, ok := selection.Attr("property")
+		if !ok {
+			key, _ = selection.Attr("name")
+		}
+		content, _ := selection.Attr("content")
+		properties = append(properties, applink.Property{Name: key, Content: html.UnescapeString(content)})
+		if parts := strings.Split(strings.ToLower(key), ":"); len(parts) > 1 {
+			platforms[parts[1]] = true
+		}
+	})
+	for _, platformPair := range twitterAppPlatforms {
+		twitterPlatform, platform := platformPair[0], platformPair[1]
+		if platforms[platform] {
+			continue
+		}
+		idProperty := "app_store_id"
+		if platform == "android" {
+			idProperty = "package"
+		}
+		for _, field := range []struct{ twitter, property string }{
+			{"url", "url"},
+			{"id", idProperty},
+			{"name", "app_name"},
+		} {
+			if value, ok := page.Tag("twitter:app:" + field.twitter + ":" + twitterPlatform); ok {
+				properties = append(properties, applink.Property{
+					Name:    "al:" + platform + ":" + field.property,
+					Content: value,
+				})
+			}
+		}
+	}
+	card.Metadata().AppLink = applink.FromProperties(properties)
+	return nil
+}
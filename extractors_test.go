@@ -0,0 +1,57 @@
+package gogetter
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JustinTulloss/gogetter/wildcard"
+)
+
+func TestCustomExtractors(t *testing.T) {
+	t.Parallel()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	headline := ExtractorFunc(func(page *Page) error {
+		page.AddTag("og:title", strings.TrimSpace(page.Doc.Find(".headline").Text()))
+		return nil
+	})
+	broken := ExtractorFunc(func(page *Page) error {
+		return errors.New("broken")
+	})
+	source := CardExtractorFunc(func(page *Page, card wildcard.Wildcard) error {
+		card.Metadata().Source = "Example"
+		return nil
+	})
+	scraper.SetExtractors(append([]Extractor{headline}, DefaultExtractors()...))
+	scraper.AddExtractor(broken)
+	scraper.AddCardExtractor(source)
+	doc := `<html>
+		<head>
+			<meta property="og:title" content="Example | The headline" />
+		</head>
+		<body><h1 class="headline"> The headline </h1></body>
+	</html>`
+	expected := &wildcard.LinkCard{
+		Card: wildcard.Card{
+			CardType: wildcard.LinkType,
+			Warnings: []string{"broken"},
+		},
+		Target: &wildcard.LinkTarget{
+			GenericMetadata: wildcard.GenericMetadata{
+				Title:  "The headline",
+				Source: "Example",
+			},
+		},
+	}
+	result, err := scraper.ParseTags(strings.NewReader(doc), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("%#v != %#v", result, expected)
+	}
+}
@@ -0,0 +1,313 @@
+package gogetter
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/JustinTulloss/gogetter/wildcard"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html/charset"
+)
+
+// Feeds can get big, and we only care about the first few items anyway.
+const maxFeedBytes = 2 * 1024 * 1024
+
+// How many of the latest items we put on a feed card.
+const maxFeedItems = 10
+
+// Content types that mean the response is definitely a feed.
+var feedContentTypes = map[string]bool{
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+	"application/rdf+xml":   true,
+	"application/rss+xml":   true,
+}
+
+// Content types that might be a feed, depending on what's inside.
+var maybeFeedContentTypes = map[string]bool{
+	"application/json": true,
+	"application/xml":  true,
+	"text/xml":         true,
+}
+
+// Finds the feeds a page advertises with <link rel="alternate">.
+func discoverFeeds(doc *goquery.Document, webUrl string) []wildcard.FeedLink {
+	var feeds []wildcard.FeedLink
+	seen := make(map[string]bool)
+	doc.Find(`link[rel~="alternate"][href]`).Each(func(i int, selection *goquery.Selection) {
+		contentType, _ := selection.Attr("type")
+		contentType = strings.ToLower(strings.TrimSpace(contentType))
+		if !feedContentTypes[contentType] && contentType != "application/json" {
+			return
+		}
+		href, _ := selection.Attr("href")
+		feedUrl, ok := resolveUrl(webUrl, href)
+		if !ok || seen[feedUrl] {
+			return
+		}
+		seen[feedUrl] = true
+		title, _ := selection.Attr("title")
+		feeds = append(feeds, wildcard.FeedLink{
+			Url:         feedUrl,
+			ContentType: contentType,
+			Title:       strings.TrimSpace(title),
+		})
+	})
+	return feeds
+}
+
+// Builds a feed card for the feed being read from body, which is the
+// response for url. If it turns out not to be a feed after all, a plain link
+// card is returned instead.
+func (s *Scraper) scrapeFeed(body io.Reader, url string) (wildcard.Wildcard, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(body, maxFeedBytes))
+	if err != nil {
+		return nil, err
+	}
+	card := parseFeed(data, url)
+	if card == nil {
+		return wildcard.NewLinkCard(url, url), nil
+	}
+	return card, nil
+}
+
+// Figures out what kind of feed data is and parses it. Returns nil if it
+// isn't a feed we understand.
+func parseFeed(data []byte, url string) *wildcard.FeedCard {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		return parseJSONFeed(trimmed, url)
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = charset.NewReaderLabel
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil
+		}
+		root, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch root.Name.Local {
+		case "rss", "RDF":
+			return parseRSS(decoder, root, url)
+		case "feed":
+			return parseAtom(decoder, root, url)
+		}
+		return nil
+	}
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Links       []string `xml:"link"`
+	Description string   `xml:"description"`
+	PubDate     string   `xml:"pubDate"`
+	Date        string   `xml:"date"`
+}
+
+type rssChannel struct {
+	Title       string   `xml:"title"`
+	Links       []string `xml:"link"`
+	Description string   `xml:"description"`
+	Image       struct {
+		Url string `xml:"url"`
+	} `xml:"image"`
+	Items []rssItem `xml:"item"`
+}
+
+// RSS 2.0 puts the items inside the channel, RSS 1.0 puts them next to it.
+type rssFeed struct {
+	Channel rssChannel `xml:"channel"`
+	Image   struct {
+		Url string `xml:"url"`
+	} `xml:"image"`
+	Items []rssItem `xml:"item"`
+}
+
+func parseRSS(decoder *xml.Decoder, root xml.StartElement, url string) *wildcard.FeedCard {
+	var feed rssFeed
+	if err := decoder.DecodeElement(&feed, &root); err != nil {
+		return nil
+	}
+	card := wildcard.NewFeedCard(url, url)
+	card.Feed.Format = "rss"
+	card.Feed.Title = strings.TrimSpace(feed.Channel.Title)
+	card.Feed.Description = feedText(feed.Channel.Description)
+	image := resolveFeedUrl(url, firstNonEmpty(feed.Channel.Image.Url, feed.Image.Url))
+	if image != "" {
+		card.Feed.Image = &wildcard.ImageDetails{ImageUrl: image}
+	}
+	items := append(feed.Channel.Items, feed.Items...)
+	for _, item := range items {
+		card.Feed.Items = append(card.Feed.Items, wildcard.FeedItem{
+			Url:             resolveFeedUrl(url, firstNonEmpty(item.Links...)),
+			Title:           strings.TrimSpace(item.Title),
+			Description:     feedText(item.Description),
+			PublicationDate: feedDate(item.PubDate, item.Date),
+		})
+	}
+	finishFeed(card)
+	return card
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+type atomEntry struct {
+	Title     string     `xml:"title"`
+	Links     []atomLink `xml:"link"`
+	Summary   string     `xml:"summary"`
+	Content   string     `xml:"content"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+}
+
+type atomFeed struct {
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle"`
+	Icon     string      `xml:"icon"`
+	Logo     string      `xml:"logo"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+// Atom links without a rel are alternates, which is the one we want.
+func atomAlternate(links []atomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+func parseAtom(decoder *xml.Decoder, root xml.StartElement, url string) *wildcard.FeedCard {
+	var feed atomFeed
+	if err := decoder.DecodeElement(&feed, &root); err != nil {
+		return nil
+	}
+	card := wildcard.NewFeedCard(url, url)
+	card.Feed.Format = "atom"
+	card.Feed.Title = feedText(feed.Title)
+	card.Feed.Description = feedText(feed.Subtitle)
+	card.Feed.SourceIcon = resolveFeedUrl(url, feed.Icon)
+	if logo := resolveFeedUrl(url, feed.Logo); logo != "" {
+		card.Feed.Image = &wildcard.ImageDetails{ImageUrl: logo}
+	}
+	for _, entry := range feed.Entries {
+		card.Feed.Items = append(card.Feed.Items, wildcard.FeedItem{
+			Url:             resolveFeedUrl(url, atomAlternate(entry.Links)),
+			Title:           feedText(entry.Title),
+			Description:     feedText(firstNonEmpty(entry.Summary, entry.Content)),
+			PublicationDate: feedDate(entry.Published, entry.Updated),
+		})
+	}
+	finishFeed(card)
+	return card
+}
+
+// https://www.jsonfeed.org/version/1.1/
+type jsonFeed struct {
+	Version     string `json:"version"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+	Favicon     string `json:"favicon"`
+	Items       []struct {
+		Url           string `json:"url"`
+		Title         string `json:"title"`
+		Summary       string `json:"summary"`
+		ContentText   string `json:"content_text"`
+		ContentHtml   string `json:"content_html"`
+		DatePublished string `json:"date_published"`
+		DateModified  string `json:"date_modified"`
+	} `json:"items"`
+}
+
+func parseJSONFeed(data []byte, url string) *wildcard.FeedCard {
+	var feed jsonFeed
+	if err := json.Unmarshal(data, &feed); err != nil {
+		return nil
+	}
+	if !strings.Contains(feed.Version, "jsonfeed.org") {
+		return nil
+	}
+	card := wildcard.NewFeedCard(url, url)
+	card.Feed.Format = "json"
+	card.Feed.Title = strings.TrimSpace(feed.Title)
+	card.Feed.Description = feedText(feed.Description)
+	card.Feed.SourceIcon = resolveFeedUrl(url, feed.Favicon)
+	if icon := resolveFeedUrl(url, feed.Icon); icon != "" {
+		card.Feed.Image = &wildcard.ImageDetails{ImageUrl: icon}
+	}
+	for _, item := range feed.Items {
+		card.Feed.Items = append(card.Feed.Items, wildcard.FeedItem{
+			Url:             resolveFeedUrl(url, item.Url),
+			Title:           strings.TrimSpace(item.Title),
+			Description:     feedText(firstNonEmpty(item.Summary, item.ContentText, item.ContentHtml)),
+			PublicationDate: feedDate(item.DatePublished, item.DateModified),
+		})
+	}
+	finishFeed(card)
+	return card
+}
+
+// Puts the newest items first and keeps only as many as we want. Items
+// without dates keep their place relative to each other at the end.
+func finishFeed(card *wildcard.FeedCard) {
+	items := card.Feed.Items
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i].PublicationDate, items[j].PublicationDate
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return a.After(*b)
+	})
+	if len(items) > maxFeedItems {
+		items = items[:maxFeedItems]
+	}
+	card.Feed.Items = items
+}
+
+// Feed text is often HTML, which isn't much use on a card.
+func feedText(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.Contains(text, "<") {
+		return normalizeWhitespace(text)
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(text))
+	if err != nil {
+		return normalizeWhitespace(text)
+	}
+	return truncateText(normalizeWhitespace(doc.Text()), maxExcerptLength)
+}
+
+func feedDate(dates ...string) *time.Time {
+	for _, date := range dates {
+		if t, ok := parseDate(date); ok {
+			return &t
+		}
+	}
+	return nil
+}
+
+func resolveFeedUrl(base, ref string) string {
+	if strings.TrimSpace(ref) == "" {
+		return ""
+	}
+	resolved, ok := resolveUrl(base, ref)
+	if !ok {
+		return ""
+	}
+	return resolved
+}
@@ -0,0 +1,87 @@
+package gogetter
+
+import (
+	"testing"
+)
+
+var testFeeds = []struct {
+	format string
+	data   string
+}{
+	{"rss", `<?xml version="1.0" encoding="ISO-8859-1"?>
+		<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+			<channel>
+				<title>Example News</title>
+				<link>http://example.com/</link>
+				<atom:link href="http://example.com/feed.xml" rel="self" />
+				<description>&lt;p&gt;All the news that fits&lt;/p&gt;</description>
+				<image><url>/logo.png</url></image>
+				<item>
+					<title>Older</title>
+					<link>http://example.com/older</link>
+					<pubDate>Tue, 03 Mar 2015 10:00:00 GMT</pubDate>
+				</item>
+				<item>
+					<title>Newer</title>
+					<link>http://example.com/newer</link>
+					<pubDate>Wed, 04 Mar 2015 10:00:00 GMT</pubDate>
+				</item>
+			</channel>
+		</rss>`},
+	{"atom", `<?xml version="1.0" encoding="utf-8"?>
+		<feed xmlns="http://www.w3.org/2005/Atom">
+			<title>Example News</title>
+			<subtitle>All the news that fits</subtitle>
+			<logo>/logo.png</logo>
+			<entry>
+				<title>Older</title>
+				<link href="http://example.com/older" />
+				<updated>2015-03-03T10:00:00Z</updated>
+			</entry>
+			<entry>
+				<title>Newer</title>
+				<link rel="alternate" href="/newer" />
+				<published>2015-03-04T10:00:00Z</published>
+			</entry>
+		</feed>`},
+	{"json", `{
+			"version": "https://jsonfeed.org/version/1.1",
+			"title": "Example News",
+			"description": "All the news that fits",
+			"icon": "http://example.com/logo.png",
+			"items": [
+				{"id": "1", "url": "http://example.com/older", "title": "Older", "date_published": "2015-03-03T10:00:00Z"},
+				{"id": "2", "url": "http://example.com/newer", "title": "Newer", "date_published": "2015-03-04T10:00:00Z"}
+			]
+		}`},
+}
+
+func TestParseFeed(t *testing.T) {
+	t.Parallel()
+	for _, test := range testFeeds {
+		card := parseFeed([]byte(test.data), "http://example.com/feed")
+		if card == nil {
+			t.Errorf("%s: not recognized as a feed", test.format)
+			continue
+		}
+		feed := card.Feed
+		if feed.Format != test.format {
+			t.Errorf("%s: format was %q", test.format, feed.Format)
+		}
+		if feed.Title != "Example News" || feed.Description != "All the news that fits" {
+			t.Errorf("%s: title %q, description %q", test.format, feed.Title, feed.Description)
+		}
+		if feed.Image == nil || feed.Image.ImageUrl != "http://example.com/logo.png" {
+			t.Errorf("%s: image was %#v", test.format, feed.Image)
+		}
+		if len(feed.Items) != 2 || feed.Items[0].Title != "Newer" || feed.Items[0].Url != "http://example.com/newer" {
+			t.Errorf("%s: items were %#v", test.format, feed.Items)
+		}
+	}
+	if parseFeed([]byte(`<html><body>Not a feed</body></html>`), "http://example.com/") != nil {
+		t.Errorf("Expected html not to be a feed")
+	}
+	if parseFeed([]byte(`{"some": "json"}`), "http://example.com/") != nil {
+		t.Errorf("Expected arbitrary json not to be a feed")
+	}
+}
@@ -1,11 +1,13 @@
 package gogetter
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"html"
 	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
@@ -15,46 +17,113 @@ import (
 
 	"github.com/JustinTulloss/gogetter/wildcard"
 	"github.com/PuerkitoBio/goquery"
-	"github.com/facebookgo/httpcontrol"
 	"github.com/mitchellh/mapstructure"
 	"github.com/temoto/robotstxt.go"
 )
 
 // There are potentially a ton of these as any facebook app can enter their own
-// prefixes.
+// prefixes. These are the ones every scraper reads; AddPrefixes adds more, and
+// pages that declare their prefixes get those read too.
 var ogPrefixes = []string{"og", "airbedandbreakfast", "twitter"}
 
 const DEFAULT_UA = "Gogetter (https://github.com/JustinTulloss/gogetter) (like GoogleBot and facebookexternalhit/1.1 and Twitterbot/1.0)"
 
 // A Scraper instance can be used to scrape webpages for metadata.
 type Scraper struct {
-	useragent            string
-	shouldCheckRobotsTxt bool
-	client               *http.Client
+	useragent                  string
+	shouldCheckRobotsTxt       bool
+	shouldProbeImages          bool
+	shouldIncludeImageLocation bool
+	prefixes                   []string
+	aliases                    map[string][]string
+	extractors                 []Extractor
+	cardExtractors             []CardExtractor
+	rules                      *RulesExtractor
+	shouldRecordProvenance     bool
+	invalidCardPolicy          InvalidCardPolicy
+	imageUrlRewriter           ImageUrlRewriter
+	client                     *http.Client
 }
 
+// The aliases every scraper starts out with. SetAliases and AddAliases change
+// them for a single scraper.
 var tagAliases = map[string][]string{
-	"al:android:url":         {"twitter:app:url:googleplay"},
-	"al:ipad:url":            {"twitter:app:url:ipad"},
-	"al:iphone:url":          {"twitter:app:url:iphone"},
-	"article:published_time": {"article:published"},
-	"byl":            {"author"},
-	"og:description": {"twitter:description", "description"},
-	"og:image":       {"twitter:image"},
-	"og:site_name":   {"cre"},
-	"og:title":       {"twitter:title", "title"},
+	"article:published_time": {
+		"article:published",
+		"schema:datePublished",
+		"citation_publication_date",
+		"citation_online_date",
+		"citation_date",
+		"dcterms:issued",
+		"dcterms:created",
+		"dcterms:date",
+		"dc:date.issued",
+		"dc:date.created",
+		"dc:date",
+		"time:datetime",
+	},
+	"business:contact_data:country_name":           {"schema:address:addressCountry"},
+	"business:contact_data:locality":               {"schema:address:addressLocality"},
+	"business:contact_data:phone_number":           {"schema:telephone"},
+	"business:contact_data:post_office_box_number": {"schema:address:postOfficeBoxNumber"},
+	"business:contact_data:postal_code":            {"schema:address:postalCode"},
+	"business:contact_data:region":                 {"schema:address:addressRegion"},
+	"business:contact_data:street_address":         {"schema:address:streetAddress"},
+	"byl":                                          {"author", "schema:author", "dc:creator", "dcterms:creator"},
+	"keywords":                                     {"dc:subject", "dcterms:subject"},
+	"og:description": {
+		"twitter:description",
+		"description",
+		"citation_abstract",
+		"schema:description",
+		"dcterms:abstract",
+		"dcterms:description",
+		"dc:description",
+		"content:excerpt",
+	},
+	"og:image":                 {"twitter:image", "schema:image", "image:fallback"},
+	"og:image:height":          {"image:fallback:height"},
+	"og:image:width":           {"image:fallback:width"},
+	"og:site_name":             {"cre", "schema:publisher", "dcterms:publisher", "dc:publisher"},
+	"og:title":                 {"twitter:title", "citation_title", "schema:headline", "schema:name", "dcterms:title", "dc:title", "title"},
+	"og:type":                  {"schema:type"},
+	"og:video:height":          {"twitter:player:height"},
+	"og:video:url":             {"og:video:secure_url", "og:video"},
+	"og:video:width":           {"twitter:player:width"},
+	"place:location:altitude":  {"schema:geo:elevation"},
+	"place:location:latitude":  {"schema:geo:latitude"},
+	"place:location:longitude": {"schema:geo:longitude"},
+	"product:availability":     {"schema:offers:availability"},
+	"product:brand":            {"schema:brand"},
+	"product:price:amount":     {"schema:offers:price", "schema:offers:lowPrice"},
+	"product:price:currency":   {"schema:offers:priceCurrency"},
+	"product:retailer_item_id": {"schema:sku"},
+	"rating:best":              {"schema:aggregateRating:bestRating"},
+	"rating:count":             {"schema:aggregateRating:ratingCount"},
+	"rating:review_count":      {"schema:aggregateRating:reviewCount"},
+	"rating:value":             {"schema:aggregateRating:ratingValue"},
+	"rating:worst":             {"schema:aggregateRating:worstRating"},
+	"twitter:player":           {"video:player"},
 }
 
 // Finds other names for the same value and puts it in the map
 // under the name we prefer.
-func resolveAliases(tags map[string]string) {
+//
+// Aliases are only looked up among the tags the page actually had. A tag
+// that's an alias of another and has aliases of its own would otherwise
+// depend on the order we happened to resolve them in.
+func resolveAliases(tags map[string]string, tagAliases map[string][]string) {
+	pageTags := make(map[string]string, len(tags))
+	for tag, val := range tags {
+		pageTags[tag] = val
+	}
 	for tag, aliases := range tagAliases {
 		_, ok := tags[tag]
 		if ok {
 			continue
 		}
 		for _, alias := range aliases {
-			val, ok := tags[alias]
+			val, ok := pageTags[alias]
 			if ok {
 				tags[tag] = val
 				break
@@ -63,21 +132,46 @@ func resolveAliases(tags map[string]string) {
 	}
 }
 
+// Every scraper gets its own copy of the aliases so they can be changed
+// without affecting the others.
+func copyAliases(tagAliases map[string][]string) map[string][]string {
+	aliases := make(map[string][]string, len(tagAliases))
+	for tag, names := range tagAliases {
+		aliases[tag] = append([]string(nil), names...)
+	}
+	return aliases
+}
+
+// Whether tag or any of its aliases has a value.
+func hasTag(tags map[string]string, tagAliases map[string][]string, tag string) bool {
+	if _, ok := tags[tag]; ok {
+		return true
+	}
+	for _, alias := range tagAliases[tag] {
+		if _, ok := tags[alias]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 // Used by recusivelyDecode to look at every field in a struct
-func iterateOverFields(value reflect.Value, tags map[string]string) error {
+func iterateOverFields(value reflect.Value, tags map[string]string, warnings *[]string) error {
 	for i := 0; i < value.NumField(); i++ {
 		field := value.Field(i)
 		structField := value.Type().Field(i)
-		if field.Kind() == reflect.Ptr && field.CanSet() && structField.Tag.Get("ogtag") != "" {
+		// Only fields tagged with just options, like ",fill", are structs
+		// for us to look inside. Other pointers are left to mapstructure.
+		if field.Kind() == reflect.Ptr && field.CanSet() && strings.HasPrefix(structField.Tag.Get("ogtag"), ",") {
 			if field.IsNil() && field.CanSet() {
 				field.Set(reflect.New(field.Type().Elem()))
 			}
-			err := recursivelyDecode(tags, field.Interface())
+			err := recursivelyDecode(tags, field.Interface(), warnings)
 			if err != nil {
 				return err
 			}
 		} else if field.Kind() == reflect.Struct && structField.Anonymous {
-			err := iterateOverFields(field, tags)
+			err := iterateOverFields(field, tags, warnings)
 			if err != nil {
 				return err
 			}
@@ -91,8 +185,13 @@ func iterateOverFields(value reflect.Value, tags map[string]string) error {
 // flat map, we can just iterate through every struct that might potentially
 // have tags (as indicated by the `ogtag` struct tag) and try to match the
 // tags to the fields using mapstructure.
-func recursivelyDecode(tags map[string]string, result interface{}) error {
+//
+// Publishers put all sorts of junk in their tags, so a field that fails to
+// decode is left empty and described in warnings instead of failing the
+// whole thing.
+func recursivelyDecode(tags map[string]string, result interface{}, warnings *[]string) error {
 	decoderConfig := &mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.ComposeDecodeHookFunc(decodeDateHook, decodeListHook),
 		WeaklyTypedInput: true,
 		TagName:          "ogtag",
 		Result:           result,
@@ -102,19 +201,89 @@ func recursivelyDecode(tags map[string]string, result interface{}) error {
 		return err
 	}
 	err = decoder.Decode(tags)
-	if err != nil {
+	if decodeErr, ok := err.(*mapstructure.Error); ok {
+		*warnings = append(*warnings, decodeErr.Errors...)
+	} else if err != nil {
 		return err
 	}
 	value := reflect.Indirect(reflect.ValueOf(result))
-	return iterateOverFields(value, tags)
+	return iterateOverFields(value, tags, warnings)
+}
+
+// Decoding allocates every struct a tag might end up in, and mapstructure
+// allocates pointers it has nothing to put in, so afterwards there are empty
+// images, zero dates and the like all over the card. This walks the card and
+// drops any optional (omitempty) pointer that still points at a zero value,
+// so that missing data is missing rather than empty.
+func pruneEmptyFields(value reflect.Value) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		structField := value.Type().Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		switch {
+		case field.Kind() == reflect.Ptr && !field.IsNil():
+			elem := field.Elem()
+			if elem.Kind() == reflect.Struct {
+				pruneEmptyFields(elem)
+			}
+			if elem.IsZero() && strings.Contains(structField.Tag.Get("json"), ",omitempty") {
+				field.Set(reflect.Zero(field.Type()))
+			}
+		case field.Kind() == reflect.Struct && structField.Anonymous:
+			pruneEmptyFields(field)
+		}
+	}
 }
 
-func convertTagsToCard(tags map[string]string, webUrl string) (wildcard.Wildcard, error) {
-	resolveAliases(tags)
+// Lists come to us as a single tag, like keywords separated by semicolons.
+func decodeListHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() == reflect.String && to == reflect.TypeOf([]string(nil)) {
+		return splitKeywords(data.(string)), nil
+	}
+	return data, nil
+}
+
+func convertTagsToCard(tags map[string]string, tagAliases map[string][]string, webUrl string) (wildcard.Wildcard, error) {
+	resolveAliases(tags, tagAliases)
 	ogType, ok := tags["og:type"]
 	if !ok {
 		ogType = "website"
 	}
+	// Journals tend to call their article pages websites.
+	if _, ok := tags["citation_title"]; ok && ogType == "website" {
+		ogType = "article"
+	}
+	ogType = upgradeCardType(tags, ogType)
+	card, warnings, err := decodeCard(tags, ogType, webUrl)
+	if err != nil {
+		return nil, err
+	}
+	// Pages don't always have what their type promises, like a video page
+	// with no video. Rather than hand out a card nobody can use, we make
+	// the first simpler one out of the same tags that has what it needs.
+	if missing := missingEssentials(card); len(missing) > 0 {
+		for fallback := fallbackCardType(card); fallback != ""; {
+			fallbackCard, fallbackWarnings, err := decodeCard(tags, fallback, webUrl)
+			if err != nil {
+				return nil, err
+			}
+			if len(missingEssentials(fallbackCard)) == 0 {
+				warning := fmt.Sprintf("Fell back from %s to %s card: %s", card.BaseCard().CardType, fallbackCard.BaseCard().CardType, missing[0])
+				card, warnings = fallbackCard, append([]string{warning}, fallbackWarnings...)
+				break
+			}
+			fallback = fallbackCardType(fallbackCard)
+		}
+	}
+	card.BaseCard().Warnings = warnings
+	return card, nil
+}
+
+// Makes the card for a page that says it's ogType out of tags. Returns the
+// problems decoding ran into.
+func decodeCard(tags map[string]string, ogType string, webUrl string) (wildcard.Wildcard, []string, error) {
 	var card wildcard.Wildcard
 	url, ok := tags["og:url"]
 	if !ok {
@@ -123,6 +292,12 @@ func convertTagsToCard(tags map[string]string, webUrl string) (wildcard.Wildcard
 	switch ogType {
 	case "article":
 		card = wildcard.NewArticleCard(webUrl, url)
+	case "product", "product.item":
+		card = wildcard.NewProductCard(webUrl, url)
+	case "place", "business.business", "restaurant.restaurant":
+		place := wildcard.NewPlaceCard(webUrl)
+		place.Place.Url = url
+		card = place
 	case "video":
 		fallthrough
 	case "video.episode":
@@ -134,11 +309,13 @@ func convertTagsToCard(tags map[string]string, webUrl string) (wildcard.Wildcard
 	default:
 		card = wildcard.NewLinkCard(webUrl, url)
 	}
-	err := recursivelyDecode(tags, card)
+	var warnings []string
+	err := recursivelyDecode(tags, card, &warnings)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return card, nil
+	pruneEmptyFields(reflect.ValueOf(card).Elem())
+	return card, warnings, nil
 }
 
 func (s *Scraper) buildRequest(url string) (*http.Request, error) {
@@ -151,133 +328,340 @@ func (s *Scraper) buildRequest(url string) (*http.Request, error) {
 	return req, nil
 }
 
-func (s *Scraper) checkRobotsTxt(fullUrl string) (bool, error) {
+// Asks the server for part of url, as described by byteRange, and reads at
+// most limit bytes of it. Servers that don't support ranges would send us
+// the whole file, so we give up on them.
+func (s *Scraper) fetchRange(url string, byteRange string, limit int) ([]byte, error) {
+	req, err := s.buildRequest(url)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", byteRange)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("Could not fetch %s of %s (%d)", byteRange, url, resp.StatusCode)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, int64(limit)))
+}
+
+func (s *Scraper) checkRobotsTxt(ctx context.Context, fullUrl string) (bool, error) {
 	if !s.shouldCheckRobotsTxt {
 		return true, nil
 	}
-	parsed, err := url.Parse(fullUrl)
+	robots, path, err := s.fetchRobotsTxt(ctx, fullUrl)
 	if err != nil {
 		return false, err
 	}
+	return s.robotsTxtPermits(robots, path), nil
+}
+
+// Whether robots, which came from fetchRobotsTxt, lets us fetch path.
+func (s *Scraper) robotsTxtPermits(robots *robotstxt.RobotsData, path string) bool {
+	if robots == nil {
+		// Assume we can crawl if the robots.txt file doesn't work
+		return true
+	}
+	return robots.TestAgent(path, s.useragent)
+}
+
+// Fetches the robots.txt that covers fullUrl. Also returns the path of
+// fullUrl, which is what robots.txt rules are about. The robots.txt is nil
+// if the site doesn't have a usable one.
+func (s *Scraper) fetchRobotsTxt(ctx context.Context, fullUrl string) (*robotstxt.RobotsData, string, error) {
+	parsed, err := url.Parse(fullUrl)
+	if err != nil {
+		return nil, "", err
+	}
 	original := parsed.Path
 	parsed.Path = "robots.txt"
 	parsed.RawQuery = ""
 	req, err := s.buildRequest(parsed.String())
 	if err != nil {
-		return false, err
+		return nil, "", err
 	}
-	resp, err := s.client.Do(req)
+	resp, err := s.client.Do(req.WithContext(ctx))
 	if err != nil {
-		return false, err
+		return nil, "", err
 	}
 	defer resp.Body.Close()
-	robots, err := robotstxt.FromResponse(resp)
-	if robots == nil {
-		// Assume we can crawl if the robots.txt file doesn't work
-		return true, nil
-	}
-	return robots.TestAgent(original, s.useragent), nil
+	robots, _ := robotstxt.FromResponse(resp)
+	return robots, original, nil
 }
 
 var rawMetaTags = []string{"cre", "byl", "author"}
 
+// Runs the scraper's extractors over the page at r, which came from webUrl,
+// and builds a card out of what they find.
 func (s *Scraper) ParseTags(r io.Reader, webUrl string) (wildcard.Wildcard, error) {
 	doc, err := goquery.NewDocumentFromReader(r)
 	if err != nil {
 		return nil, err
 	}
-	results := make(map[string]string)
-	// First we deal with a couple special tags to get the title
-	// and the favicon
-	title := doc.Find("title").Text()
-	if title != "" {
-		results["title"] = html.UnescapeString(title)
-	}
-	favicon, ok := doc.Find("link[rel~=icon]").Attr("href")
-	if ok {
-		faviconUrl, err := url.Parse(favicon)
-		if err == nil {
-			if faviconUrl.Scheme == "" {
-				faviconUrl.Scheme = "http"
-			}
-			if faviconUrl.Host == "" {
-				u, _ := url.Parse(webUrl)
-				faviconUrl.Host = u.Host
-			}
-			results["favicon"] = faviconUrl.String()
-		}
-	}
-	// Find all meta tags for all different og prefixes we support
-	tags := doc.Find(`meta[name="description"]`)
-	for _, metaTag := range rawMetaTags {
-		tags = tags.Add(fmt.Sprintf(`meta[name="%s"]`, metaTag))
+	page := s.newPage(doc, webUrl)
+	var recorder *provenanceRecorder
+	if s.shouldRecordProvenance {
+		recorder = newProvenanceRecorder(s.aliases)
 	}
-	for _, prefix := range ogPrefixes {
-		tags = tags.Add(fmt.Sprintf(`meta[property^="%s:"]`, prefix))
-		tags = tags.Add(fmt.Sprintf(`meta[name^="%s:"]`, prefix))
+	problems := s.runExtractors(page, recorder)
+	recorder.snapshot(page.tags)
+	card, err := convertTagsToCard(page.tags, s.aliases, webUrl)
+	if err != nil {
+		return nil, err
 	}
-	// For all the tags, extract the content
-	tags.Each(func(i int, selection *goquery.Selection) {
-		key, ok := selection.Attr("name")
-		if !ok {
-			key, _ = selection.Attr("property")
+	recorder.decoded(card)
+	for _, extractor := range s.cardExtractors {
+		if err := recorder.extractCard(extractor, page, card); err != nil {
+			problems = append(problems, err.Error())
 		}
-		content, _ := selection.Attr("content")
-		// Open graph defers to the first tag that we understand.
-		_, alreadySet := results[key]
-		if !alreadySet {
-			results[key] = html.UnescapeString(content)
+	}
+	recorder.finish(card)
+	// A broken extractor shouldn't cost us everything the others found.
+	for _, problem := range problems {
+		addWarning(card, problem)
+	}
+	return s.checkCard(card)
+}
+
+func (s *Scraper) runExtractors(page *Page, recorder *provenanceRecorder) []string {
+	var problems []string
+	for _, extractor := range s.extractors {
+		if err := recorder.extract(extractor, page); err != nil {
+			problems = append(problems, err.Error())
 		}
-	})
-	card, err := convertTagsToCard(results, webUrl)
+	}
+	return problems
+}
+
+// Returns everything the extractors found on the page before any of it is
+// made into a card, with every value of tags that show up more than once.
+// The first value of each tag is the one the card would get. Aliases aren't
+// resolved, so this is the place to look when a card is missing something.
+func (s *Scraper) ParseRawTags(r io.Reader, webUrl string) (map[string][]string, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
 	if err != nil {
 		return nil, err
 	}
-	return card, nil
+	page := s.newPage(doc, webUrl)
+	// Problems don't matter much here, whatever was found is still returned.
+	s.runExtractors(page, nil)
+	return page.RawTags(), nil
 }
 
-func (s *Scraper) ScrapeTags(url string) (interface{}, error) {
-	permitted, err := s.checkRobotsTxt(url)
+// Fetches url and returns its raw tags, see ParseRawTags. Only HTML pages
+// have tags.
+func (s *Scraper) ScrapeRawTags(url string) (map[string][]string, error) {
+	resp, err := s.fetch(context.Background(), url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	contentType, body, err := sniffContentType(resp.Header.Get("Content-Type"), resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if !htmlContentTypes[contentType] {
+		return nil, errors.New(fmt.Sprintf("%s is %s, which doesn't have tags", url, contentType))
+	}
+	return s.ParseRawTags(body, url)
+}
+
+// Fetches url if robots.txt lets us. Anything but a 200 is an error.
+func (s *Scraper) fetch(ctx context.Context, url string) (*http.Response, error) {
+	permitted, err := s.checkRobotsTxt(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	if !permitted {
 		return nil, errors.New(fmt.Sprintf("Not permitted to fetch %s", url))
 	}
+	return s.get(ctx, url)
+}
+
+// Fetches url without looking at robots.txt. Anything but a 200 is an
+// error.
+func (s *Scraper) get(ctx context.Context, url string) (*http.Response, error) {
 	req, err := s.buildRequest(url)
-	resp, err := s.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
 	if resp.StatusCode != 200 {
+		resp.Body.Close()
 		errMsg := fmt.Sprintf("Could not fetch %s (%d)", url, resp.StatusCode)
 		return nil, errors.New(errMsg)
 	}
-	contentType := resp.Header.Get("Content-Type")
-	if contentType == "" || strings.Contains(contentType, "text/html") {
-		return s.ParseTags(resp.Body, url)
+	return resp, nil
+}
+
+func (s *Scraper) ScrapeTags(url string) (interface{}, error) {
+	resp, err := s.fetch(context.Background(), url)
+	if err != nil {
+		return nil, err
 	}
-	// We can't really trust the Content-Type header, so we take
-	// a look at what actually gets returned.
-	contentStart, err := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	defer resp.Body.Close()
+	contentType, body, err := sniffContentType(resp.Header.Get("Content-Type"), resp.Body)
 	if err != nil {
-		contentType = http.DetectContentType(contentStart)
+		return nil, err
+	}
+	if htmlContentTypes[contentType] {
+		card, err := s.ParseTags(body, url)
+		if err != nil {
+			return nil, err
+		}
+		if s.shouldProbeImages {
+			s.probeCardImages(card)
+			pruneProvenance(card)
+		}
+		s.rewriteCardImages(card)
+		return card, nil
 	}
+	var card wildcard.Wildcard
 	switch {
+	case feedContentTypes[contentType] || maybeFeedContentTypes[contentType]:
+		card, err = s.scrapeFeed(body, url)
+	case contentType == "application/pdf":
+		card, err = s.scrapePDF(body, url)
 	case strings.HasPrefix(contentType, "image"):
-		card := wildcard.NewImageCard(url, url)
-		card.Media.ImageContentType = contentType
-		return card, nil
+		card, err = s.scrapeImage(body, url, contentType)
 	case strings.HasPrefix(contentType, "video"):
-		card := wildcard.NewVideoCard(url)
-		card.Media.StreamUrl = url
-		card.Media.StreamContentType = contentType
-		return card, nil
+		card, err = s.scrapeVideo(body, url, contentType)
 	default:
-		card := wildcard.NewLinkCard(url, url)
-		return card, nil
+		card = wildcard.NewLinkCard(url, url)
+	}
+	if err != nil {
+		return nil, err
 	}
+	if card, err = s.checkCard(card); err != nil {
+		return nil, err
+	}
+	s.rewriteCardImages(card)
+	return card, nil
+}
+
+// When enabled, ScrapeTags fetches the start of every image it puts on a
+// card to find out its real size and type, and drops images that are
+// broken. This costs a request per image, so it's off by default.
+func (s *Scraper) SetProbeImages(shouldProbeImages bool) {
+	s.shouldProbeImages = shouldProbeImages
+}
+
+// Content types we parse as HTML. XHTML is close enough that the HTML parser
+// copes with it fine.
+var htmlContentTypes = map[string]bool{
+	"text/html":             true,
+	"application/xhtml+xml": true,
+}
+
+// Content types that don't tell us anything about what's actually in the
+// response.
+var genericContentTypes = map[string]bool{
+	"":                         true,
+	"application/octet-stream": true,
+	"application/unknown":      true,
+	"application/x-unknown":    true,
+	"binary/octet-stream":      true,
+	"text/plain":               true,
+}
+
+// Some HTML doesn't start with anything http.DetectContentType recognizes,
+// so we look a little further in for tags that only show up in HTML.
+var htmlMarkers = [][]byte{[]byte("<html"), []byte("<head"), []byte("<body"), []byte("<meta"), []byte("<title")}
+
+// We can't really trust the Content-Type header, so when it's missing or too
+// vague to be useful we take a look at what actually gets returned. Returns
+// the media type without any parameters along with a reader that still has
+// the whole body in it.
+func sniffContentType(header string, body io.Reader) (string, io.Reader, error) {
+	contentType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		// A header we can't parse is as good as no header at all.
+		contentType = ""
+	}
+	contentType = strings.ToLower(contentType)
+	if !genericContentTypes[contentType] {
+		return contentType, body, nil
+	}
+	contentStart, err := ioutil.ReadAll(io.LimitReader(body, 512))
+	if err != nil {
+		return "", nil, err
+	}
+	body = io.MultiReader(bytes.NewReader(contentStart), body)
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(contentStart))
+	if sniffed == "text/plain" {
+		lowered := bytes.ToLower(contentStart)
+		for _, marker := range htmlMarkers {
+			if bytes.Contains(lowered, marker) {
+				return "text/html", body, nil
+			}
+		}
+	}
+	return sniffed, body, nil
+}
+
+// Images straight off a camera or phone often say where they were taken,
+// which people rarely mean to share. We leave that off image cards unless
+// this is enabled.
+func (s *Scraper) SetIncludeImageLocation(shouldIncludeImageLocation bool) {
+	s.shouldIncludeImageLocation = shouldIncludeImageLocation
+}
+
+// Has ParseTags read meta tags with these prefixes too, on top of the ones
+// we always read and any the page declares itself.
+func (s *Scraper) AddPrefixes(prefixes ...string) {
+	s.prefixes = append(s.prefixes, prefixes...)
+}
+
+// Replaces the other names tag can go by, in the order they're tried when
+// tag itself isn't on the page. With no aliases, tag only goes by its name.
+func (s *Scraper) SetAliases(tag string, aliases ...string) {
+	if len(aliases) == 0 {
+		delete(s.aliases, tag)
+		return
+	}
+	s.aliases[tag] = aliases
+}
+
+// Adds other names tag can go by, tried after the ones it already has.
+func (s *Scraper) AddAliases(tag string, aliases ...string) {
+	s.aliases[tag] = append(s.aliases[tag], aliases...)
+}
+
+// Replaces the extractors ParseTags runs, in order. Since the first one to
+// find a tag wins, put site specific extractors ahead of the defaults to have
+// them take precedence.
+func (s *Scraper) SetExtractors(extractors []Extractor) {
+	s.extractors = extractors
+}
+
+// Adds an extractor after the ones already there, so it only fills in tags
+// nothing else found.
+func (s *Scraper) AddExtractor(extractor Extractor) {
+	s.extractors = append(s.extractors, extractor)
+}
+
+// Replaces the card extractors ParseTags runs once the card is built.
+func (s *Scraper) SetCardExtractors(cardExtractors []CardExtractor) {
+	s.cardExtractors = cardExtractors
+}
+
+// Adds a card extractor after the ones already there.
+func (s *Scraper) AddCardExtractor(cardExtractor CardExtractor) {
+	s.cardExtractors = append(s.cardExtractors, cardExtractor)
+}
+
+// Replaces the client pages, robots.txt and images are fetched with. The
+// default one won't connect to our own network, since anyone who can pick a
+// url to scrape could otherwise use us to reach it. Only swap in one that
+// doesn't have that check when every url comes from someone you trust.
+func (s *Scraper) SetClient(client *http.Client) {
+	s.client = client
 }
 
 // Creates a new scraper. If no user agent is provided, DEFAULT_UA is used.
@@ -286,15 +670,14 @@ func NewScraper(ua string, shouldCheckRobotsTxt bool) (*Scraper, error) {
 	if err != nil {
 		return nil, err
 	}
+	transport := newSafeTransport()
+	// This is a one off scrape job, no reason to keep the connection
+	// around.
+	transport.DisableKeepAlives = true
 	client := &http.Client{
-		Transport: &httpcontrol.Transport{
-			// This is a one off scrape job, no reason to keep the
-			// connection around.
-			DisableKeepAlives: true,
-			RequestTimeout:    1 * time.Minute,
-			MaxTries:          3,
-		},
-		Jar: jar,
+		Transport: &retryTransport{transport: transport, maxTries: 3},
+		Timeout:   1 * time.Minute,
+		Jar:       jar,
 	}
 	if ua == "" {
 		ua = DEFAULT_UA
@@ -302,6 +685,10 @@ func NewScraper(ua string, shouldCheckRobotsTxt bool) (*Scraper, error) {
 	return &Scraper{
 		useragent:            ua,
 		shouldCheckRobotsTxt: shouldCheckRobotsTxt,
+		prefixes:             append([]string(nil), ogPrefixes...),
+		aliases:              copyAliases(tagAliases),
+		extractors:           DefaultExtractors(),
+		cardExtractors:       DefaultCardExtractors(),
 		client:               client,
 	}, nil
 }
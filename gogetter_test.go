@@ -1,6 +1,7 @@
 package gogetter
 
 import (
+	"io/ioutil"
 	"reflect"
 
 	"github.com/JustinTulloss/gogetter/applink"
@@ -32,14 +33,6 @@ var testdocs = []doctest{
 			Target: &wildcard.LinkTarget{
 				GenericMetadata: wildcard.GenericMetadata{
 					Title: "More interesting",
-					AppLink: &applink.AppLink{
-						Ios:     &applink.Ios{},
-						Iphone:  &applink.Iphone{},
-						Ipad:    &applink.Ipad{},
-						Android: &applink.Android{},
-					},
-					Image:           &wildcard.ImageDetails{},
-					PublicationDate: &time.Time{},
 				},
 			},
 		},
@@ -55,14 +48,6 @@ var testdocs = []doctest{
 			Target: &wildcard.LinkTarget{
 				GenericMetadata: wildcard.GenericMetadata{
 					Title: "relevant",
-					AppLink: &applink.AppLink{
-						Ios:     &applink.Ios{},
-						Iphone:  &applink.Iphone{},
-						Ipad:    &applink.Ipad{},
-						Android: &applink.Android{},
-					},
-					Image:           &wildcard.ImageDetails{},
-					PublicationDate: &time.Time{},
 				},
 			},
 		},
@@ -88,21 +73,165 @@ var testdocs = []doctest{
 			},
 			Target: &wildcard.LinkTarget{
 				Description: "pod (plain old descriptions) work",
+			},
+		},
+	},
+	{
+		`<html>
+			<head>
+				<meta name="DC.date" content="2015-03-04" />
+				<script type="application/ld+json">
+					{"@context": "http://schema.org", "@graph": [{"@type": "NewsArticle", "datePublished": "2015-03-04T10:00:00-0800"}]}
+				</script>
+			</head>
+		</html>`,
+		&wildcard.ArticleCard{
+			Card: wildcard.Card{
+				CardType: wildcard.ArticleType,
+			},
+			Article: &wildcard.Article{
+				GenericMetadata: wildcard.GenericMetadata{
+					PublicationDate: timePtr(time.Date(2015, 3, 4, 10, 0, 0, 0, time.FixedZone("", -8*60*60))),
+				},
+			},
+		},
+	},
+	{
+		`<html prefix="article: http://ogp.me/ns/article#">
+			<head>
+				<meta property="article:published_time" content="sometime last week" />
+			</head>
+		</html>`,
+		&wildcard.LinkCard{
+			Card: wildcard.Card{
+				CardType: wildcard.LinkType,
+				Warnings: []string{
+					`error decoding 'article:published_time': "sometime last week" is not a date we understand`,
+				},
+			},
+			Target: &wildcard.LinkTarget{},
+		},
+	},
+	{
+		`<meta property="og:title" content="Still here" />
+		<meta property="og:image" content="http://example.com/a.png" />
+		<meta property="og:image:width" content="auto" />
+		<meta property="og:image:height" content="300" />`,
+		&wildcard.LinkCard{
+			Card: wildcard.Card{
+				CardType: wildcard.LinkType,
+				Warnings: []string{
+					`cannot parse 'og:image:width' as int: strconv.ParseInt: parsing "auto": invalid syntax`,
+				},
+			},
+			Target: &wildcard.LinkTarget{
+				GenericMetadata: wildcard.GenericMetadata{
+					Title: "Still here",
+					Image: &wildcard.ImageDetails{
+						ImageUrl: "http://example.com/a.png",
+						Height:   300,
+					},
+				},
+			},
+		},
+	},
+	{
+		`<html>
+			<body>
+				<div class="nav"><a href="/">Home</a> <a href="/about">About us and our many offices</a></div>
+				<div class="post-content">
+					<h1>A headline</h1>
+					<p>The first paragraph of the story, which goes on for a while, has commas, and says things.</p>
+					<p>A second paragraph that is also long enough to count as real content on the page.</p>
+				</div>
+				<div class="comments"><p>First! This comment is long enough to look like a paragraph.</p></div>
+			</body>
+		</html>`,
+		&wildcard.LinkCard{
+			Card: wildcard.Card{
+				CardType: wildcard.LinkType,
+			},
+			Target: &wildcard.LinkTarget{
+				Description: "The first paragraph of the story, which goes on for a while, has commas, and says things. A second paragraph that is also long enough to count as real content on the page.",
+				GenericMetadata: wildcard.GenericMetadata{
+					WordCount:   33,
+					ReadingTime: 1,
+				},
+			},
+		},
+	},
+	{
+		`<meta property="al:ios:url" content="example://story/1" />
+		<meta property="al:ios:app_store_id" content="12345" />
+		<meta property="al:ios:app_name" content="Example" />
+		<meta property="al:ios:url" content="example-lite://story/1" />
+		<meta property="al:ios:app_name" content="Example Lite" />
+		<meta property="al:android:url" content="example://story/1" />
+		<meta property="al:android:package" content="com.example" />
+		<meta property="al:android:class" content="com.example.StoryActivity" />
+		<meta property="al:windows_universal:url" content="example://story/1" />
+		<meta property="al:windows_universal:app_id" content="a1b2c3" />
+		<meta property="al:web:should_fallback" content="false" />
+		<meta name="twitter:app:url:iphone" content="example-iphone://story/1" />
+		<meta name="twitter:app:id:iphone" content="67890" />
+		<meta name="twitter:app:url:googleplay" content="ignored://story/1" />`,
+		&wildcard.LinkCard{
+			Card: wildcard.Card{
+				CardType: wildcard.LinkType,
+			},
+			Target: &wildcard.LinkTarget{
 				GenericMetadata: wildcard.GenericMetadata{
 					AppLink: &applink.AppLink{
-						Ios:     &applink.Ios{},
-						Iphone:  &applink.Iphone{},
-						Ipad:    &applink.Ipad{},
-						Android: &applink.Android{},
+						Ios: []*applink.Ios{
+							{Url: "example://story/1", AppStoreId: "12345", AppName: "Example"},
+							{Url: "example-lite://story/1", AppName: "Example Lite"},
+						},
+						Iphone: []*applink.Iphone{
+							{Url: "example-iphone://story/1", AppStoreId: "67890"},
+						},
+						Android: []*applink.Android{
+							{Url: "example://story/1", Package: "com.example", Class: "com.example.StoryActivity"},
+						},
+						WindowsUniversal: []*applink.Windows{
+							{Url: "example://story/1", AppId: "a1b2c3"},
+						},
+						Web: &applink.Web{},
 					},
-					Image:           &wildcard.ImageDetails{},
-					PublicationDate: &time.Time{},
+				},
+			},
+		},
+	},
+	{
+		`<script type="application/ld+json">
+			{"@context": "https://schema.org", "@type": "Product", "name": "Widget", "sku": "W-1",
+			 "offers": {"@type": "Offer", "price": 19.99, "priceCurrency": "USD"},
+			 "aggregateRating": {"@type": "AggregateRating", "ratingValue": "4.5", "bestRating": "5", "ratingCount": 12}}
+		</script>`,
+		&wildcard.ProductCard{
+			Card: wildcard.Card{
+				CardType: wildcard.ProductType,
+			},
+			Product: &wildcard.Product{
+				Sku:      "W-1",
+				Price:    "19.99",
+				Currency: "USD",
+				Rating: &wildcard.Rating{
+					Value:       "4.5",
+					BestRating:  "5",
+					RatingCount: 12,
+				},
+				GenericMetadata: wildcard.GenericMetadata{
+					Title: "Widget",
 				},
 			},
 		},
 	},
 }
 
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
 func TestParseTags(t *testing.T) {
 	t.Parallel()
 	scraper, err := NewScraper("", false)
@@ -121,3 +250,217 @@ func TestParseTags(t *testing.T) {
 		}
 	}
 }
+
+func TestFallbackImage(t *testing.T) {
+	t.Parallel()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	doc := `<html>
+		<head>
+			<link rel="apple-touch-icon" href="/touch.png" />
+		</head>
+		<body>
+			<img src="http://ads.example.com/ads/banner.jpg" width="728" height="90" />
+			<img src="/pixel.gif" width="1" height="1" />
+			<img src="/logo.png" width="40" height="40" />
+			<article>
+				<img src="/images/hero.jpg" width="800" height="450" />
+			</article>
+		</body>
+	</html>`
+	expected := &wildcard.LinkCard{
+		Card: wildcard.Card{
+			CardType: wildcard.LinkType,
+			WebUrl:   "http://example.com/story",
+		},
+		Target: &wildcard.LinkTarget{
+			Url: "http://example.com/story",
+			GenericMetadata: wildcard.GenericMetadata{
+				Image: &wildcard.ImageDetails{
+					ImageUrl: "http://example.com/images/hero.jpg",
+					Width:    800,
+					Height:   450,
+				},
+			},
+		},
+	}
+	result, err := scraper.ParseTags(strings.NewReader(doc), "http://example.com/story")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("%#v != %#v", result, expected)
+	}
+}
+
+func TestSniffContentType(t *testing.T) {
+	t.Parallel()
+	page := "<!DOCTYPE html><html><head><title>Hi</title></head></html>"
+	cases := []struct {
+		header      string
+		body        string
+		contentType string
+	}{
+		{"text/html; charset=utf-8", page, "text/html"},
+		{"application/xhtml+xml", page, "application/xhtml+xml"},
+		{"", page, "text/html"},
+		{"text/plain", page, "text/html"},
+		{"application/octet-stream", "\x89PNG\x0d\x0a\x1a\x0a", "image/png"},
+		{"text/plain", "just some words", "text/plain"},
+		{"not a; valid=header=", "random gobbleygook <title>hi</title>", "text/html"},
+		{"image/jpeg", "whatever", "image/jpeg"},
+	}
+	for _, c := range cases {
+		contentType, body, err := sniffContentType(c.header, strings.NewReader(c.body))
+		if err != nil {
+			t.Errorf("%q: %s", c.header, err)
+			continue
+		}
+		if contentType != c.contentType {
+			t.Errorf("%q: sniffed %q, expected %q", c.header, contentType, c.contentType)
+		}
+		// Sniffing must not eat the start of the body.
+		content, _ := ioutil.ReadAll(body)
+		if string(content) != c.body {
+			t.Errorf("%q: body came back as %q", c.header, content)
+		}
+	}
+}
+
+func TestParseRawTags(t *testing.T) {
+	t.Parallel()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	doc := `<html><head>
+		<title>A title</title>
+		<meta property="og:title" content="The og title" />
+		<meta property="og:image" content="http://example.com/1.jpg" />
+		<meta property="og:image" content="http://example.com/2.jpg" />
+		<meta property="og:image" content="http://example.com/1.jpg" />
+		<meta name="twitter:label" content="go" />
+		<meta name="twitter:label" content="html" />
+	</head><body></body></html>`
+	result, err := scraper.ParseRawTags(strings.NewReader(doc), "http://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{
+		"title":         {"A title"},
+		"og:title":      {"The og title"},
+		"og:image":      {"http://example.com/1.jpg", "http://example.com/2.jpg"},
+		"twitter:label": {"go", "html"},
+	}
+	for tag, values := range expected {
+		if !reflect.DeepEqual(result[tag], values) {
+			t.Errorf("%s: %#v != %#v", tag, result[tag], values)
+		}
+	}
+}
+
+func TestResolveAliasesIsStable(t *testing.T) {
+	t.Parallel()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	doc := `<html><head>
+		<meta property="og:type" content="video" />
+		<meta property="og:title" content="A video" />
+		<meta property="og:video" content="http://example.com/player" />
+		<meta property="og:video:secure_url" content="https://example.com/player" />
+		<meta property="og:video:type" content="text/html" />
+	</head></html>`
+	expected := map[string]string{
+		"media.embedded_url": "video:player",
+		"media.stream_url":   "og:video:secure_url",
+	}
+	// Go picks a different map order every time, so a few tries should
+	// catch one that depends on it.
+	for i := 0; i < 50; i++ {
+		result, err := scraper.WithProvenance().ParseTags(strings.NewReader(doc), "http://example.com/video")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sources := make(map[string]string)
+		for path, provenance := range result.BaseCard().Provenance {
+			if _, ok := expected[path]; ok {
+				sources[path] = provenance.Tag
+			}
+		}
+		if !reflect.DeepEqual(sources, expected) {
+			t.Fatalf("%#v != %#v", sources, expected)
+		}
+		video := result.(*wildcard.VideoCard)
+		if video.Media.EmbeddedUrl != "https://example.com/player" {
+			t.Fatalf("Expected the https player, got %q", video.Media.EmbeddedUrl)
+		}
+	}
+}
+
+func TestVideoFilesArentPlayers(t *testing.T) {
+	t.Parallel()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	doc := `<html><head>
+		<meta property="og:type" content="video.other" />
+		<meta property="og:title" content="A video" />
+		<meta property="og:video" content="http://example.com/video.mp4" />
+	</head></html>`
+	result, err := scraper.WithProvenance().ParseTags(strings.NewReader(doc), "http://example.com/video")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if video, ok := result.(*wildcard.VideoCard); ok && video.Media.EmbeddedUrl != "" {
+		t.Errorf("An mp4 shouldn't be embedded, got %q", video.Media.EmbeddedUrl)
+	}
+	for path := range result.BaseCard().Provenance {
+		if strings.HasSuffix(path, ".embedded_url") {
+			t.Errorf("Unexpected embedded url from %#v", result.BaseCard().Provenance[path])
+		}
+	}
+}
+
+func TestRawTagsKeepRepeatedValues(t *testing.T) {
+	t.Parallel()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	doc := `<html><head>
+		<meta name="DC.creator" content="Jane Smith" />
+		<meta name="DC.creator" content="John Doe" />
+		<meta name="DC.subject" content="go" />
+		<meta name="DC.subject" content="html" />
+	</head><body>
+		<div itemscope itemtype="https://schema.org/Article">
+			<span itemprop="headline">A headline</span>
+			<span itemprop="author">Jane Smith</span>
+			<span itemprop="author">John Doe</span>
+		</div>
+		<div vocab="http://purl.org/dc/terms/">
+			<span property="contributor">Someone</span>
+			<span property="contributor">Someone Else</span>
+		</div>
+	</body></html>`
+	result, err := scraper.ParseRawTags(strings.NewReader(doc), "http://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{
+		"dc:creator":          {"Jane Smith", "John Doe"},
+		"dc:subject":          {"go; html", "go", "html"},
+		"schema:author":       {"Jane Smith", "John Doe"},
+		"dcterms:contributor": {"Someone", "Someone Else"},
+	}
+	for tag, values := range expected {
+		if !reflect.DeepEqual(result[tag], values) {
+			t.Errorf("%s: %#v != %#v", tag, result[tag], values)
+		}
+	}
+}
@@ -0,0 +1,530 @@
+package gogetter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/JustinTulloss/gogetter/wildcard"
+)
+
+// How much of an image we read looking for metadata. EXIF, IPTC and XMP all
+// live in the header, so this is plenty for all but the strangest files.
+const maxImageMetadataBytes = 256 * 1024
+
+// Cameras helpfully fill in the description with their own name.
+var junkCaptions = regexp.MustCompile(`(?i)^\s*(|olympus digital camera|sony dsc|digital camera|default|untitled|image|photo|picture|scanned image)\s*$`)
+
+// What the people who made an image had to say about it.
+type imageMetadata struct {
+	Caption     string
+	Artist      string
+	Copyright   string
+	CaptureDate *time.Time
+	Latitude    *float64
+	Longitude   *float64
+}
+
+// Builds an image card for the image being read from body, which is the
+// response for url. Only the start of the image is read.
+func (s *Scraper) scrapeImage(body io.Reader, url, contentType string) (*wildcard.ImageCard, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(body, maxImageMetadataBytes))
+	if err != nil {
+		return nil, err
+	}
+	card := wildcard.NewImageCard(url, url)
+	card.Media.ImageContentType = contentType
+	if info, err := decodeImageHeader(data); err == nil {
+		card.Media.Width = info.Width
+		card.Media.Height = info.Height
+	}
+	metadata := readImageMetadata(data)
+	card.Media.ImageCaption = metadata.Caption
+	card.Media.Author = metadata.Artist
+	card.Media.Copyright = metadata.Copyright
+	card.Media.CaptureDate = metadata.CaptureDate
+	if s.shouldIncludeImageLocation && metadata.Latitude != nil && metadata.Longitude != nil {
+		card.Media.Location = &wildcard.GeoCoordinates{
+			Latitude:  metadata.Latitude,
+			Longitude: metadata.Longitude,
+		}
+	}
+	return card, nil
+}
+
+// Reads whatever metadata we can find in the start of an image file. XMP is
+// the most likely to have been written by a person, then IPTC, then EXIF,
+// so that's the order they take precedence in.
+func readImageMetadata(data []byte) *imageMetadata {
+	var exif, xmp, iptc []byte
+	text := make(map[string]string)
+	switch {
+	case bytes.HasPrefix(data, []byte("\xff\xd8")):
+		exif, xmp, iptc = jpegMetadataSegments(data)
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		exif, xmp = pngMetadataChunks(data, text)
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		exif, xmp = webpMetadataChunks(data)
+	case bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*")):
+		exif = data
+	}
+	if xmp == nil {
+		// Plenty of formats just drop the packet in somewhere.
+		xmp = findXMPPacket(data)
+	}
+
+	metadata := &imageMetadata{}
+	var xmpProperties map[string][]string
+	if xmp != nil {
+		xmpProperties = readXMP(xmp)
+	}
+	iptcRecords := readIPTC(iptc)
+	exifTags := readEXIF(exif)
+
+	metadata.Caption = firstCaption(
+		firstValue(xmpProperties, "dc:description"),
+		iptcRecords[iptcCaption],
+		exifTags.Strings[exifImageDescription],
+		text["Description"],
+		text["Title"],
+	)
+	metadata.Artist = firstNonEmpty(
+		strings.Join(xmpProperties["dc:creator"], ", "),
+		iptcRecords[iptcByline],
+		exifTags.Strings[exifArtist],
+		text["Author"],
+	)
+	metadata.Copyright = firstNonEmpty(
+		firstValue(xmpProperties, "dc:rights"),
+		iptcRecords[iptcCopyright],
+		exifTags.Strings[exifCopyright],
+		text["Copyright"],
+	)
+	for _, date := range []string{
+		firstValue(xmpProperties, "exif:DateTimeOriginal"),
+		firstValue(xmpProperties, "photoshop:DateCreated"),
+		firstValue(xmpProperties, "xmp:CreateDate"),
+		iptcDate(iptcRecords[iptcDateCreated], iptcRecords[iptcTimeCreated]),
+		exifTags.Strings[exifDateTimeOriginal] + exifTags.Strings[exifOffsetTimeOriginal],
+		exifTags.Strings[exifDateTime],
+		text["Creation Time"],
+	} {
+		if t, ok := parseDate(date); ok {
+			metadata.CaptureDate = &t
+			break
+		}
+	}
+	metadata.Latitude, metadata.Longitude = exifTags.Latitude, exifTags.Longitude
+	return metadata
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func firstCaption(values ...string) string {
+	for _, value := range values {
+		if !junkCaptions.MatchString(value) {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// Walks the segments at the start of a JPEG until the image data starts,
+// picking out the ones that hold metadata.
+func jpegMetadataSegments(data []byte) (exif, xmp, iptc []byte) {
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			return
+		}
+		marker := data[i+1]
+		// Start of scan means the image data is next and there's no more
+		// metadata to be had.
+		if marker == 0xda || marker == 0xd9 {
+			return
+		}
+		if marker == 0xff || (marker >= 0xd0 && marker <= 0xd7) || marker == 0x01 {
+			i += 2
+			continue
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return
+		}
+		segment := data[i+4 : end]
+		switch {
+		case marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")):
+			exif = segment[6:]
+		case marker == 0xe1 && bytes.HasPrefix(segment, []byte("http://ns.adobe.com/xap/1.0/\x00")):
+			xmp = segment[29:]
+		case marker == 0xed && bytes.HasPrefix(segment, []byte("Photoshop 3.0\x00")):
+			iptc = photoshopIPTC(segment[14:])
+		}
+		i = end
+	}
+	return
+}
+
+// Photoshop keeps IPTC in an image resource block alongside a bunch of
+// other things we don't care about.
+func photoshopIPTC(data []byte) []byte {
+	for i := 0; i+12 <= len(data); {
+		if string(data[i:i+4]) != "8BIM" {
+			return nil
+		}
+		id := binary.BigEndian.Uint16(data[i+4 : i+6])
+		// The name is a padded pascal string.
+		nameLength := int(data[i+6])
+		nameSize := nameLength + 1
+		if nameSize%2 == 1 {
+			nameSize++
+		}
+		sizeAt := i + 6 + nameSize
+		if sizeAt+4 > len(data) {
+			return nil
+		}
+		size := int(binary.BigEndian.Uint32(data[sizeAt : sizeAt+4]))
+		start := sizeAt + 4
+		if size < 0 || start+size > len(data) {
+			return nil
+		}
+		if id == 0x0404 {
+			return data[start : start+size]
+		}
+		i = start + size + size%2
+	}
+	return nil
+}
+
+// PNG keeps EXIF in an eXIf chunk, XMP in an iTXt chunk and everything else
+// in text chunks, which we collect into text by keyword.
+func pngMetadataChunks(data []byte, text map[string]string) (exif, xmp []byte) {
+	for i := 8; i+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[i : i+4]))
+		kind := string(data[i+4 : i+8])
+		start := i + 8
+		if length < 0 || start+length > len(data) {
+			return
+		}
+		chunk := data[start : start+length]
+		switch kind {
+		case "eXIf":
+			exif = chunk
+		case "tEXt":
+			if parts := bytes.SplitN(chunk, []byte{0}, 2); len(parts) == 2 {
+				text[string(parts[0])] = latin1(parts[1])
+			}
+		case "zTXt":
+			if parts := bytes.SplitN(chunk, []byte{0}, 2); len(parts) == 2 && len(parts[1]) > 1 {
+				if inflated, err := inflate(parts[1][1:]); err == nil {
+					text[string(parts[0])] = latin1(inflated)
+				}
+			}
+		case "iTXt":
+			keyword, value := pngInternationalText(chunk)
+			if keyword == "XML:com.adobe.xmp" {
+				xmp = value
+			} else if keyword != "" {
+				text[keyword] = string(value)
+			}
+		case "IDAT", "IEND":
+			return
+		}
+		// Skip the CRC too.
+		i = start + length + 4
+	}
+	return
+}
+
+// iTXt is keyword, null, compression flag, compression method, language
+// tag, null, translated keyword, null, then the text.
+func pngInternationalText(chunk []byte) (string, []byte) {
+	parts := bytes.SplitN(chunk, []byte{0}, 2)
+	if len(parts) != 2 || len(parts[1]) < 2 {
+		return "", nil
+	}
+	keyword := string(parts[0])
+	compressed := parts[1][0] == 1
+	rest := bytes.SplitN(parts[1][2:], []byte{0}, 3)
+	if len(rest) != 3 {
+		return "", nil
+	}
+	value := rest[2]
+	if compressed {
+		inflated, err := inflate(value)
+		if err != nil {
+			return "", nil
+		}
+		value = inflated
+	}
+	return keyword, value
+}
+
+func inflate(data []byte) ([]byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(io.LimitReader(reader, maxImageMetadataBytes))
+}
+
+func webpMetadataChunks(data []byte) (exif, xmp []byte) {
+	for i := 12; i+8 <= len(data); {
+		kind := string(data[i : i+4])
+		length := int(binary.LittleEndian.Uint32(data[i+4 : i+8]))
+		start := i + 8
+		if length < 0 || start+length > len(data) {
+			return
+		}
+		switch kind {
+		case "EXIF":
+			exif = bytes.TrimPrefix(data[start:start+length], []byte("Exif\x00\x00"))
+		case "XMP ":
+			xmp = data[start : start+length]
+		}
+		i = start + length + length%2
+	}
+	return
+}
+
+// EXIF tags we read, from the first IFD and the EXIF IFD it points to.
+const (
+	exifImageDescription   = 0x010e
+	exifDateTime           = 0x0132
+	exifArtist             = 0x013b
+	exifCopyright          = 0x8298
+	exifIFDPointer         = 0x8769
+	exifGPSPointer         = 0x8825
+	exifDateTimeOriginal   = 0x9003
+	exifOffsetTimeOriginal = 0x9011
+
+	gpsLatitudeRef  = 0x0001
+	gpsLatitude     = 0x0002
+	gpsLongitudeRef = 0x0003
+	gpsLongitude    = 0x0004
+)
+
+type exifData struct {
+	Strings   map[uint16]string
+	Latitude  *float64
+	Longitude *float64
+}
+
+// Reads the tags we're interested in out of a TIFF structure, which is what
+// EXIF is stored as.
+func readEXIF(data []byte) *exifData {
+	exif := &exifData{Strings: make(map[uint16]string)}
+	if len(data) < 8 {
+		return exif
+	}
+	var order binary.ByteOrder
+	switch string(data[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return exif
+	}
+	ifd0 := readIFD(data, order, int(order.Uint32(data[4:8])))
+	for tag, entry := range ifd0 {
+		if entry.Type == tiffASCII {
+			exif.Strings[tag] = entry.String()
+		}
+	}
+	if pointer, ok := ifd0[exifIFDPointer]; ok {
+		for tag, entry := range readIFD(data, order, int(pointer.Uint())) {
+			if entry.Type == tiffASCII {
+				exif.Strings[tag] = entry.String()
+			}
+		}
+	}
+	if pointer, ok := ifd0[exifGPSPointer]; ok {
+		gps := readIFD(data, order, int(pointer.Uint()))
+		exif.Latitude = gpsCoordinate(gps[gpsLatitude], gps[gpsLatitudeRef], "S")
+		exif.Longitude = gpsCoordinate(gps[gpsLongitude], gps[gpsLongitudeRef], "W")
+	}
+	return exif
+}
+
+const (
+	tiffASCII    = 2
+	tiffShort    = 3
+	tiffLong     = 4
+	tiffRational = 5
+)
+
+var tiffTypeSizes = map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 7: 1, 9: 4, 10: 8}
+
+type ifdEntry struct {
+	Type  uint16
+	Value []byte
+	order binary.ByteOrder
+}
+
+func (e *ifdEntry) String() string {
+	value := string(bytes.TrimRight(e.Value, "\x00 "))
+	if !utf8.ValidString(value) {
+		return latin1([]byte(value))
+	}
+	return value
+}
+
+func (e *ifdEntry) Uint() uint32 {
+	switch {
+	case e.Type == tiffShort && len(e.Value) >= 2:
+		return uint32(e.order.Uint16(e.Value))
+	case e.Type == tiffLong && len(e.Value) >= 4:
+		return e.order.Uint32(e.Value)
+	}
+	return 0
+}
+
+func (e *ifdEntry) Rationals() []float64 {
+	if e.Type != tiffRational {
+		return nil
+	}
+	var values []float64
+	for i := 0; i+8 <= len(e.Value); i += 8 {
+		numerator := e.order.Uint32(e.Value[i : i+4])
+		denominator := e.order.Uint32(e.Value[i+4 : i+8])
+		if denominator == 0 {
+			return nil
+		}
+		values = append(values, float64(numerator)/float64(denominator))
+	}
+	return values
+}
+
+func readIFD(data []byte, order binary.ByteOrder, offset int) map[uint16]*ifdEntry {
+	entries := make(map[uint16]*ifdEntry)
+	if offset < 8 || offset+2 > len(data) {
+		return entries
+	}
+	count := int(order.Uint16(data[offset : offset+2]))
+	for i := 0; i < count; i++ {
+		at := offset + 2 + i*12
+		if at+12 > len(data) {
+			break
+		}
+		tag := order.Uint16(data[at : at+2])
+		kind := order.Uint16(data[at+2 : at+4])
+		n := int(order.Uint32(data[at+4 : at+8]))
+		if n > len(data) {
+			continue
+		}
+		size := n * tiffTypeSizes[kind]
+		if size <= 0 {
+			continue
+		}
+		// Values that fit in four bytes are stored in place of the offset.
+		value := data[at+8 : at+12]
+		if size > 4 {
+			valueAt := int(order.Uint32(data[at+8 : at+12]))
+			if valueAt < 0 || valueAt+size > len(data) {
+				continue
+			}
+			value = data[valueAt : valueAt+size]
+		} else {
+			value = value[:size]
+		}
+		entries[tag] = &ifdEntry{Type: kind, Value: value, order: order}
+	}
+	return entries
+}
+
+// GPS coordinates are stored as degrees, minutes and seconds along with
+// which hemisphere they're in.
+func gpsCoordinate(value, ref *ifdEntry, negative string) *float64 {
+	if value == nil {
+		return nil
+	}
+	parts := value.Rationals()
+	if len(parts) != 3 {
+		return nil
+	}
+	coordinate := parts[0] + parts[1]/60 + parts[2]/3600
+	if ref != nil && strings.EqualFold(ref.String(), negative) {
+		coordinate = -coordinate
+	}
+	return &coordinate
+}
+
+// IPTC datasets we read, all from the application record.
+const (
+	iptcByline      = 80
+	iptcDateCreated = 55
+	iptcTimeCreated = 60
+	iptcCopyright   = 116
+	iptcCaption     = 120
+)
+
+// Reads the application record out of IPTC IIM data. Repeated datasets
+// like bylines are joined together.
+func readIPTC(data []byte) map[int]string {
+	records := make(map[int]string)
+	for i := 0; i+5 <= len(data); {
+		if data[i] != 0x1c {
+			break
+		}
+		record := data[i+1]
+		dataset := int(data[i+2])
+		length := int(binary.BigEndian.Uint16(data[i+3 : i+5]))
+		// Extended lengths are only used for things far bigger than text.
+		if length&0x8000 != 0 {
+			break
+		}
+		start := i + 5
+		if start+length > len(data) {
+			break
+		}
+		if record == 2 {
+			value := data[start : start+length]
+			text := string(value)
+			if !utf8.Valid(value) {
+				text = latin1(value)
+			}
+			if existing, ok := records[dataset]; ok && dataset == iptcByline {
+				text = existing + ", " + text
+			}
+			records[dataset] = strings.TrimSpace(text)
+		}
+		i = start + length
+	}
+	return records
+}
+
+// IPTC splits the date (CCYYMMDD) and time (HHMMSS±HHMM) into separate
+// datasets.
+func iptcDate(date, clock string) string {
+	if len(date) != 8 {
+		return ""
+	}
+	value := date[0:4] + "-" + date[4:6] + "-" + date[6:8]
+	if len(clock) >= 6 {
+		value += "T" + clock[0:2] + ":" + clock[2:4] + ":" + clock[4:6] + clock[6:]
+	}
+	return value
+}
+
+func latin1(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
@@ -0,0 +1,154 @@
+package gogetter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+type testIFDEntry struct {
+	tag   uint16
+	kind  uint16
+	count uint32
+	value []byte
+}
+
+func asciiEntry(tag uint16, value string) testIFDEntry {
+	return testIFDEntry{tag, tiffASCII, uint32(len(value) + 1), append([]byte(value), 0)}
+}
+
+func longEntry(tag uint16, value uint32) testIFDEntry {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, value)
+	return testIFDEntry{tag, tiffLong, 1, b}
+}
+
+func rationalEntry(tag uint16, values ...uint32) testIFDEntry {
+	var b []byte
+	for _, value := range values {
+		b = binary.LittleEndian.AppendUint32(b, value)
+		b = binary.LittleEndian.AppendUint32(b, 1)
+	}
+	return testIFDEntry{tag, tiffRational, uint32(len(values)), b}
+}
+
+// Appends a little endian IFD to tiff, with any values that don't fit in
+// the entries right after it. Returns the new tiff and where the IFD is.
+func appendIFD(tiff []byte, entries ...testIFDEntry) ([]byte, uint32) {
+	offset := uint32(len(tiff))
+	valuesAt := offset + 2 + uint32(len(entries))*12 + 4
+	var values []byte
+	tiff = binary.LittleEndian.AppendUint16(tiff, uint16(len(entries)))
+	for _, entry := range entries {
+		tiff = binary.LittleEndian.AppendUint16(tiff, entry.tag)
+		tiff = binary.LittleEndian.AppendUint16(tiff, entry.kind)
+		tiff = binary.LittleEndian.AppendUint32(tiff, entry.count)
+		if len(entry.value) <= 4 {
+			tiff = append(tiff, append(entry.value, make([]byte, 4-len(entry.value))...)...)
+		} else {
+			tiff = binary.LittleEndian.AppendUint32(tiff, valuesAt+uint32(len(values)))
+			values = append(values, entry.value...)
+		}
+	}
+	tiff = append(tiff, 0, 0, 0, 0)
+	return append(tiff, values...), offset
+}
+
+func TestReadImageMetadata(t *testing.T) {
+	t.Parallel()
+	tiff := []byte("II*\x00\x00\x00\x00\x00")
+	tiff, exifIFD := appendIFD(tiff,
+		asciiEntry(exifDateTimeOriginal, "2015:03:04 10:00:00"),
+		asciiEntry(exifOffsetTimeOriginal, "-08:00"),
+	)
+	tiff, gpsIFD := appendIFD(tiff,
+		asciiEntry(gpsLatitudeRef, "N"),
+		rationalEntry(gpsLatitude, 37, 46, 30),
+		asciiEntry(gpsLongitudeRef, "W"),
+		rationalEntry(gpsLongitude, 122, 25, 12),
+	)
+	tiff, ifd0 := appendIFD(tiff,
+		asciiEntry(exifImageDescription, "OLYMPUS DIGITAL CAMERA"),
+		asciiEntry(exifArtist, "Jo Photographer"),
+		asciiEntry(exifCopyright, "(c) 2015 Jo Photographer"),
+		longEntry(exifIFDPointer, exifIFD),
+		longEntry(exifGPSPointer, gpsIFD),
+	)
+	binary.LittleEndian.PutUint32(tiff[4:8], ifd0)
+
+	caption := "The bridge at dusk"
+	iptc := append([]byte{0x1c, 2, iptcCaption, 0, byte(len(caption))}, caption...)
+	resource := append([]byte("8BIM\x04\x04\x00\x00"), 0, 0, 0, byte(len(iptc)))
+	resource = append(resource, iptc...)
+
+	jpeg := []byte{0xff, 0xd8}
+	jpeg = appendJPEGSegment(jpeg, 0xe1, append([]byte("Exif\x00\x00"), tiff...))
+	jpeg = appendJPEGSegment(jpeg, 0xed, append([]byte("Photoshop 3.0\x00"), resource...))
+	jpeg = append(jpeg, 0xff, 0xda)
+
+	metadata := readImageMetadata(jpeg)
+	if metadata.Caption != caption {
+		t.Errorf("Caption was %q", metadata.Caption)
+	}
+	if metadata.Artist != "Jo Photographer" {
+		t.Errorf("Artist was %q", metadata.Artist)
+	}
+	if metadata.Copyright != "(c) 2015 Jo Photographer" {
+		t.Errorf("Copyright was %q", metadata.Copyright)
+	}
+	captured := time.Date(2015, 3, 4, 10, 0, 0, 0, time.FixedZone("", -8*60*60))
+	if metadata.CaptureDate == nil || !metadata.CaptureDate.Equal(captured) {
+		t.Errorf("Capture date was %v", metadata.CaptureDate)
+	}
+	if metadata.Latitude == nil || *metadata.Latitude != 37.775 {
+		t.Errorf("Latitude was %v", metadata.Latitude)
+	}
+	if metadata.Longitude == nil || *metadata.Longitude != -122.42 {
+		t.Errorf("Longitude was %v", metadata.Longitude)
+	}
+}
+
+func appendJPEGSegment(jpeg []byte, marker byte, segment []byte) []byte {
+	jpeg = append(jpeg, 0xff, marker)
+	jpeg = binary.BigEndian.AppendUint16(jpeg, uint16(len(segment)+2))
+	return append(jpeg, segment...)
+}
+
+func TestScrapeImageLocation(t *testing.T) {
+	t.Parallel()
+	tiff := []byte("II*\x00\x00\x00\x00\x00")
+	tiff, gpsIFD := appendIFD(tiff,
+		asciiEntry(gpsLatitudeRef, "N"),
+		rationalEntry(gpsLatitude, 37, 46, 30),
+		asciiEntry(gpsLongitudeRef, "W"),
+		rationalEntry(gpsLongitude, 122, 25, 12),
+	)
+	tiff, ifd0 := appendIFD(tiff, longEntry(exifGPSPointer, gpsIFD))
+	binary.LittleEndian.PutUint32(tiff[4:8], ifd0)
+	jpeg := []byte{0xff, 0xd8}
+	jpeg = appendJPEGSegment(jpeg, 0xe1, append([]byte("Exif\x00\x00"), tiff...))
+	jpeg = append(jpeg, 0xff, 0xda)
+
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	card, err := scraper.scrapeImage(bytes.NewReader(jpeg), "http://example.com/a.jpg", "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card.Media.Location != nil {
+		t.Errorf("Location should be left off by default, got %#v", card.Media.Location)
+	}
+
+	scraper.SetIncludeImageLocation(true)
+	card, err = scraper.scrapeImage(bytes.NewReader(jpeg), "http://example.com/a.jpg", "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	location := card.Media.Location
+	if location == nil || location.Latitude == nil || *location.Latitude != 37.775 || location.Longitude == nil || *location.Longitude != -122.42 {
+		t.Errorf("Expected the location when asked for it, got %#v", location)
+	}
+}
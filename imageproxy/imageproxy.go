@@ -0,0 +1,387 @@
+// Serves images from other sites through us, optionally resized, so the
+// people looking at cards don't have to load them from the publisher. Only
+// urls we signed are fetched, so it can't be used as an open relay.
+package imageproxy
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The widths we resize to. Other widths are rounded up to one of these so
+// the cache doesn't end up with a copy of every image at every width.
+var Widths = []int{100, 200, 400, 600, 800, 1200, 1600}
+
+// Images bigger than this aren't worth the memory it takes to decode them.
+const (
+	maxImageBytes  = 20 * 1024 * 1024
+	maxImagePixels = 50 * 1000 * 1000
+)
+
+// How long cached images are kept by default, and how often we look for
+// ones that are too old.
+const (
+	DefaultCacheMaxAge = 7 * 24 * time.Hour
+	cacheSweepInterval = time.Hour
+)
+
+var (
+	errBadSignature = errors.New("Bad signature")
+	errNoBaseUrl    = errors.New("The proxy needs a url to be served from")
+	errNotAnImage   = errors.New("Not an image we can serve")
+)
+
+type Proxy struct {
+	// Where the proxy is served from, like https://example.com/image.
+	// Signed urls point here.
+	baseUrl   string
+	key       []byte
+	cacheDir  string
+	client    *http.Client
+	useragent string
+
+	cacheMaxAge time.Duration
+	sweepLock   sync.Mutex
+	lastSweep   time.Time
+}
+
+// Creates a proxy that signs urls with key and caches what it fetches in
+// cacheDir, if it's not empty. Cached images are deleted once they're
+// DefaultCacheMaxAge old. baseUrl is where the proxy is served from, which
+// signed urls point to, so it can't be empty. client should refuse to fetch
+// from our own network, see gogetter.NewSafeClient.
+func New(baseUrl string, key []byte, cacheDir string, client *http.Client) (*Proxy, error) {
+	if baseUrl == "" {
+		return nil, errNoBaseUrl
+	}
+	return &Proxy{
+		baseUrl:     baseUrl,
+		key:         key,
+		cacheDir:    cacheDir,
+		client:      client,
+		cacheMaxAge: DefaultCacheMaxAge,
+	}, nil
+}
+
+// Sets how long cached images are kept before they're fetched again and the
+// old copy is deleted.
+func (p *Proxy) SetCacheMaxAge(maxAge time.Duration) {
+	p.cacheMaxAge = maxAge
+}
+
+// Sets the User-Agent images are fetched with.
+func (p *Proxy) SetUserAgent(useragent string) {
+	p.useragent = useragent
+}
+
+func (p *Proxy) sign(imageUrl string) string {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(imageUrl))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (p *Proxy) verify(imageUrl, signature string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(imageUrl))
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// The signed proxy url for imageUrl, at width. A width of 0 keeps the
+// image's own size. The signature doesn't cover the width, so the same url
+// can be used for a srcset.
+func (p *Proxy) SignedUrl(imageUrl string, width int) string {
+	query := url.Values{}
+	query.Set("url", imageUrl)
+	query.Set("sig", p.sign(imageUrl))
+	if width > 0 {
+		query.Set("w", strconv.Itoa(width))
+	}
+	return p.baseUrl + "?" + query.Encode()
+}
+
+// Lets the proxy be used as a gogetter.ImageUrlRewriter.
+func (p *Proxy) RewriteImageUrl(imageUrl string) string {
+	return p.SignedUrl(imageUrl, 0)
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	imageUrl := query.Get("url")
+	if !p.verify(imageUrl, query.Get("sig")) {
+		http.Error(w, errBadSignature.Error(), http.StatusForbidden)
+		return
+	}
+	width := 0
+	if value := query.Get("w"); value != "" {
+		requested, err := strconv.Atoi(value)
+		if err != nil || requested < 0 {
+			http.Error(w, fmt.Sprintf("Bad width %q", value), http.StatusBadRequest)
+			return
+		}
+		width = roundWidth(requested)
+	}
+	contentType, data, err := p.image(imageUrl, width)
+	if err != nil {
+		status := http.StatusBadGateway
+		if err == errNotAnImage {
+			status = http.StatusUnsupportedMediaType
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(data)
+}
+
+// The smallest width we resize to that's at least width.
+func roundWidth(width int) int {
+	if width == 0 {
+		return 0
+	}
+	for _, allowed := range Widths {
+		if allowed >= width {
+			return allowed
+		}
+	}
+	return Widths[len(Widths)-1]
+}
+
+// Finds the image at imageUrl at width, in the cache or by fetching it.
+func (p *Proxy) image(imageUrl string, width int) (string, []byte, error) {
+	key := cacheKey(imageUrl, width)
+	if contentType, data, ok := p.cached(key); ok {
+		return contentType, data, nil
+	}
+	original, err := p.fetch(imageUrl)
+	if err != nil {
+		return "", nil, err
+	}
+	contentType, data, err := resize(original, width)
+	if err != nil {
+		return "", nil, err
+	}
+	p.store(key, contentType, data)
+	return contentType, data, nil
+}
+
+func (p *Proxy) fetch(imageUrl string) ([]byte, error) {
+	parsed, err := url.Parse(imageUrl)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, fmt.Errorf("Can't fetch %q", imageUrl)
+	}
+	req, err := http.NewRequest("GET", imageUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.useragent != "" {
+		req.Header.Set("User-Agent", p.useragent)
+	}
+	req.Header.Set("Accept", "image/*")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Could not fetch %s (%d)", imageUrl, resp.StatusCode)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageBytes {
+		return nil, fmt.Errorf("%s is too big", imageUrl)
+	}
+	return data, nil
+}
+
+// Scales the image in data down to width, keeping its aspect ratio. Images
+// that are already small enough are passed through as they are, once we
+// know they really are images. Everything else comes out as a JPEG, or a
+// PNG if it might be transparent.
+func resize(data []byte, width int) (string, []byte, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", nil, errNotAnImage
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return "", nil, errNotAnImage
+	}
+	if width == 0 || width >= config.Width {
+		return "image/" + format, data, nil
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", nil, errNotAnImage
+	}
+	height := config.Height * width / config.Width
+	if height < 1 {
+		height = 1
+	}
+	scaled := scale(decoded, width, height)
+	var out bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&out, scaled, &jpeg.Options{Quality: 85})
+		return "image/jpeg", out.Bytes(), err
+	}
+	err = png.Encode(&out, scaled)
+	return "image/png", out.Bytes(), err
+}
+
+// Shrinks src to width by height by averaging the pixels that end up in
+// each new one, which looks a lot better than picking one of them.
+func scale(src image.Image, width, height int) *image.NRGBA {
+	bounds := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := bounds.Min.Y + (y+1)*bounds.Dy()/height
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := bounds.Min.X + (x+1)*bounds.Dx()/width
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, count uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					count++
+				}
+			}
+			// The colors are premultiplied by alpha, NRGBA wants them
+			// without.
+			offset := dst.PixOffset(x, y)
+			if a > 0 {
+				dst.Pix[offset] = uint8(r * 0xff / a)
+				dst.Pix[offset+1] = uint8(g * 0xff / a)
+				dst.Pix[offset+2] = uint8(b * 0xff / a)
+			}
+			dst.Pix[offset+3] = uint8(a / count >> 8)
+		}
+	}
+	return dst
+}
+
+func cacheKey(imageUrl string, width int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d %s", width, imageUrl)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Cached images are kept as their content type on a line of its own,
+// followed by the image.
+func (p *Proxy) cachePath(key string) string {
+	return filepath.Join(p.cacheDir, key[:2], key)
+}
+
+func (p *Proxy) cached(key string) (string, []byte, bool) {
+	if p.cacheDir == "" {
+		return "", nil, false
+	}
+	path := p.cachePath(key)
+	if info, err := os.Stat(path); err != nil || p.tooOld(info) {
+		return "", nil, false
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", nil, false
+	}
+	newline := bytes.IndexByte(data, '\n')
+	if newline < 0 || !strings.HasPrefix(string(data[:newline]), "image/") {
+		return "", nil, false
+	}
+	return string(data[:newline]), data[newline+1:], true
+}
+
+// Caching is best effort, an image we couldn't store is fetched again next
+// time.
+func (p *Proxy) store(key, contentType string, data []byte) {
+	if p.cacheDir == "" {
+		return
+	}
+	p.maybeSweep()
+	path := p.cachePath(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	// Written somewhere else first so nobody reads half an image.
+	tmp, err := ioutil.TempFile(filepath.Dir(path), key+".tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.WriteString(contentType + "\n")
+	if err == nil {
+		_, err = tmp.Write(data)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+func (p *Proxy) tooOld(info os.FileInfo) bool {
+	return time.Since(info.ModTime()) > p.cacheMaxAge
+}
+
+// Starts deleting old images in the background, unless that was done
+// recently. Images are stored far more often than it's worth walking the
+// whole cache.
+func (p *Proxy) maybeSweep() {
+	p.sweepLock.Lock()
+	defer p.sweepLock.Unlock()
+	if time.Since(p.lastSweep) < cacheSweepInterval {
+		return
+	}
+	p.lastSweep = time.Now()
+	go p.sweep()
+}
+
+// Deletes every cached image that's too old, along with anything left over
+// from a store that didn't finish.
+func (p *Proxy) sweep() {
+	filepath.Walk(p.cacheDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		if p.tooOld(info) {
+			os.Remove(path)
+		}
+		return nil
+	})
+}
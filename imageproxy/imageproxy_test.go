@@ -0,0 +1,171 @@
+package imageproxy
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Answers every request with the same image, and counts them.
+type fakeTransport struct {
+	image    []byte
+	requests int
+}
+
+func (t *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests++
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"image/png"}},
+		Body:       ioutil.NopCloser(bytes.NewReader(t.image)),
+		Request:    req,
+	}, nil
+}
+
+func testImage(t *testing.T, width, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	var out bytes.Buffer
+	if err := png.Encode(&out, img); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestProxy(t *testing.T) {
+	t.Parallel()
+	cacheDir, err := ioutil.TempDir("", "imageproxy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cacheDir)
+	transport := &fakeTransport{image: testImage(t, 400, 200)}
+	proxy, err := New("/image", []byte("secret"), cacheDir, &http.Client{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := proxy.SignedUrl("http://example.com/a.png", 150)
+	for i := 0; i < 2; i++ {
+		recorder := httptest.NewRecorder()
+		proxy.ServeHTTP(recorder, httptest.NewRequest("GET", signed, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Unexpected status %d: %s", recorder.Code, recorder.Body.String())
+		}
+		config, _, err := image.DecodeConfig(recorder.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 150 is rounded up to 200
+		if config.Width != 200 || config.Height != 100 {
+			t.Errorf("Image is %dx%d instead of 200x100", config.Width, config.Height)
+		}
+	}
+	if transport.requests != 1 {
+		t.Errorf("Expected the second request to come from the cache, made %d requests", transport.requests)
+	}
+
+	// Someone else's url with our signature doesn't work.
+	parsed, _ := url.Parse(signed)
+	query := parsed.Query()
+	query.Set("url", "http://example.com/b.png")
+	parsed.RawQuery = query.Encode()
+	recorder := httptest.NewRecorder()
+	proxy.ServeHTTP(recorder, httptest.NewRequest("GET", parsed.String(), nil))
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected a bad signature to be forbidden, got %d", recorder.Code)
+	}
+}
+
+func TestProxyRejectsNonImages(t *testing.T) {
+	t.Parallel()
+	transport := &fakeTransport{image: []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`)}
+	proxy, err := New("/image", []byte("secret"), "", &http.Client{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	proxy.ServeHTTP(recorder, httptest.NewRequest("GET", proxy.SignedUrl("http://example.com/a.svg", 0), nil))
+	if recorder.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected a non image to be refused, got %d", recorder.Code)
+	}
+}
+
+// Every file in the cache.
+func cachedFiles(t *testing.T, cacheDir string) []string {
+	var files []string
+	err := filepath.Walk(cacheDir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			files = append(files, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestCacheExpires(t *testing.T) {
+	t.Parallel()
+	cacheDir, err := ioutil.TempDir("", "imageproxy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cacheDir)
+	transport := &fakeTransport{image: testImage(t, 400, 200)}
+	proxy, err := New("/image", []byte("secret"), cacheDir, &http.Client{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy.SetCacheMaxAge(time.Hour)
+	signed := proxy.SignedUrl("http://example.com/a.png", 0)
+	fetch := func() {
+		recorder := httptest.NewRecorder()
+		proxy.ServeHTTP(recorder, httptest.NewRequest("GET", signed, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Unexpected status %d: %s", recorder.Code, recorder.Body.String())
+		}
+	}
+	backdate := func() {
+		old := time.Now().Add(-2 * time.Hour)
+		for _, path := range cachedFiles(t, cacheDir) {
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	fetch()
+	backdate()
+	fetch()
+	if transport.requests != 2 {
+		t.Errorf("Expected an old image to be fetched again, made %d requests", transport.requests)
+	}
+
+	backdate()
+	proxy.sweep()
+	if files := cachedFiles(t, cacheDir); len(files) != 0 {
+		t.Errorf("Expected old images to be deleted, found %v", files)
+	}
+}
+
+func TestProxyNeedsBaseUrl(t *testing.T) {
+	t.Parallel()
+	if _, err := New("", []byte("secret"), "", http.DefaultClient); err == nil {
+		t.Errorf("Expected a proxy without a url to be refused")
+	}
+}
@@ -0,0 +1,177 @@
+package gogetter
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Images smaller than this in either dimension are icons, spacers or
+// tracking pixels, not something you'd want on a card.
+const minImageDimension = 50
+
+// Images we don't know the size of are assumed to be about this big, which
+// is enough to beat a thumbnail but not a declared hero image.
+const unknownImageArea = 200 * 200
+
+var (
+	junkImages = regexp.MustCompile(`(?i)pixel|spacer|blank\.|transparent|tracking|beacon|1x1|clear\.gif|spinner|loading`)
+	adImages   = regexp.MustCompile(`(?i)doubleclick|googlesyndication|adservice|adsystem|/ads?/|[/_-]ad[sx]?[/_.-]|sponsor`)
+)
+
+// Where images on the page that aren't the main content tend to live.
+const imageFurniture = "header, footer, nav, aside, form, noscript"
+
+// An image we could put on the card, along with how the page described it.
+type imageCandidate struct {
+	Url    string
+	Width  int
+	Height int
+}
+
+// Looks for the best image on a page that didn't tell us which one to use.
+// Explicit declarations win, then the most prominent image in the body, and
+// finally the icon the site uses for iOS home screens. Returns nil if nothing
+// suitable turns up.
+func findFallbackImage(doc *goquery.Document, webUrl string) *imageCandidate {
+	for _, find := range []func(*goquery.Document) *imageCandidate{
+		declaredImage,
+		bestBodyImage,
+		touchIcon,
+	} {
+		candidate := find(doc)
+		if candidate == nil {
+			continue
+		}
+		absolute, ok := resolveUrl(webUrl, candidate.Url)
+		if !ok {
+			continue
+		}
+		candidate.Url = absolute
+		return candidate
+	}
+	return nil
+}
+
+func declaredImage(doc *goquery.Document) *imageCandidate {
+	if href, ok := doc.Find(`link[rel="image_src"]`).Attr("href"); ok && href != "" {
+		return &imageCandidate{Url: href}
+	}
+	var candidate *imageCandidate
+	doc.Find(`[itemprop="image"]`).EachWithBreak(func(i int, selection *goquery.Selection) bool {
+		for _, attr := range []string{"content", "src", "href"} {
+			if value, ok := selection.Attr(attr); ok && value != "" {
+				candidate = &imageCandidate{Url: value}
+				candidate.Width, candidate.Height = declaredSize(selection)
+				return false
+			}
+		}
+		return true
+	})
+	return candidate
+}
+
+// Ranks every <img> in the body by its declared size and how close to the
+// top of the page it is.
+func bestBodyImage(doc *goquery.Document) *imageCandidate {
+	var best *imageCandidate
+	bestScore := 0.0
+	doc.Find("body img").Each(func(i int, img *goquery.Selection) {
+		src := imageSource(img)
+		if src == "" || strings.HasPrefix(src, "data:") || isJunkImage(img, src) {
+			return
+		}
+		width, height := declaredSize(img)
+		if (width > 0 && width < minImageDimension) || (height > 0 && height < minImageDimension) {
+			return
+		}
+		area := float64(unknownImageArea)
+		if width > 0 && height > 0 {
+			area = float64(width * height)
+		} else if width > 0 {
+			area = float64(width * width)
+		} else if height > 0 {
+			area = float64(height * height)
+		}
+		score := area / (1 + float64(i)*0.1)
+		if img.ParentsFiltered("article, main, figure").Length() > 0 {
+			score *= 1.5
+		}
+		if img.ParentsFiltered(imageFurniture).Length() > 0 {
+			score *= 0.25
+		}
+		if score > bestScore {
+			best = &imageCandidate{Url: src, Width: width, Height: height}
+			bestScore = score
+		}
+	})
+	return best
+}
+
+func touchIcon(doc *goquery.Document) *imageCandidate {
+	href, ok := doc.Find(`link[rel="apple-touch-icon"], link[rel="apple-touch-icon-precomposed"]`).Last().Attr("href")
+	if !ok || href == "" {
+		return nil
+	}
+	return &imageCandidate{Url: href}
+}
+
+// Lazy loading scripts tend to put a placeholder in src and the real image
+// somewhere else.
+func imageSource(img *goquery.Selection) string {
+	for _, attr := range []string{"data-src", "data-original", "data-lazy-src", "src"} {
+		if value, ok := img.Attr(attr); ok && strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+func isJunkImage(img *goquery.Selection, src string) bool {
+	if junkImages.MatchString(src) || adImages.MatchString(src) {
+		return true
+	}
+	class, _ := img.Attr("class")
+	id, _ := img.Attr("id")
+	alt, _ := img.Attr("alt")
+	names := class + " " + id
+	if adImages.MatchString(names) || junkImages.MatchString(names) {
+		return true
+	}
+	return strings.EqualFold(strings.TrimSpace(alt), "advertisement")
+}
+
+// The size an element claims to be through its attributes. Zero means we
+// don't know.
+func declaredSize(selection *goquery.Selection) (int, int) {
+	return dimensionAttr(selection, "width"), dimensionAttr(selection, "height")
+}
+
+func dimensionAttr(selection *goquery.Selection, attr string) int {
+	value, ok := selection.Attr(attr)
+	if !ok {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(value), "px"))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// Turns a possibly relative reference into an absolute URL using the page's
+// URL as the base.
+func resolveUrl(base, ref string) (string, bool) {
+	refUrl, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return "", false
+	}
+	baseUrl, err := url.Parse(base)
+	if err != nil {
+		return "", false
+	}
+	return baseUrl.ResolveReference(refUrl).String(), true
+}
@@ -0,0 +1,141 @@
+package gogetter
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Properties we look for anywhere in JSON-LD blocks, keyed as
+// "schema:<property>", for when the page's main item doesn't have them.
+var jsonLDProperties = []string{"datePublished"}
+
+// Looks through every JSON-LD block on the page for the item the page is
+// about, and the properties we understand. Blocks that don't parse are
+// skipped; plenty of sites ship broken JSON in these.
+func extractJSONLD(page *Page) error {
+	var blocks []interface{}
+	page.Doc.Find(`script[type="application/ld+json"]`).Each(func(i int, selection *goquery.Selection) {
+		var data interface{}
+		err := json.Unmarshal([]byte(strings.TrimSpace(selection.Text())), &data)
+		if err != nil {
+			return
+		}
+		blocks = append(blocks, data)
+	})
+	if item := mainSchemaItem(jsonLDItems(blocks)); item != nil {
+		addSchemaTags(item, page)
+	}
+	for _, data := range blocks {
+		for _, property := range jsonLDProperties {
+			if value, ok := findJSONLDString(data, property); ok {
+				page.AddTag("schema:"+property, value)
+			}
+		}
+	}
+	return nil
+}
+
+// Turns the top level objects in data, including the ones in an @graph, into
+// items.
+func jsonLDItems(data interface{}) []*schemaItem {
+	var items []*schemaItem
+	switch v := data.(type) {
+	case map[string]interface{}:
+		if graph, ok := v["@graph"]; ok {
+			return jsonLDItems(graph)
+		}
+		items = append(items, jsonLDItem(v))
+	case []interface{}:
+		for _, value := range v {
+			items = append(items, jsonLDItems(value)...)
+		}
+	}
+	return items
+}
+
+func jsonLDItem(object map[string]interface{}) *schemaItem {
+	item := &schemaItem{}
+	switch types := object["@type"].(type) {
+	case string:
+		item.Types = append(item.Types, schemaName(types))
+	case []interface{}:
+		for _, itemType := range types {
+			if itemType, ok := itemType.(string); ok {
+				item.Types = append(item.Types, schemaName(itemType))
+			}
+		}
+	}
+	for key, value := range object {
+		if strings.HasPrefix(key, "@") {
+			continue
+		}
+		addJSONLDValue(item, schemaName(key), value)
+	}
+	return item
+}
+
+func addJSONLDValue(item *schemaItem, name string, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		item.add(name, v)
+	case float64:
+		item.add(name, strconv.FormatFloat(v, 'f', -1, 64))
+	case bool:
+		item.add(name, strconv.FormatBool(v))
+	case map[string]interface{}:
+		// {"@value": ...} is JSON-LD for a plain value with a type or
+		// language attached.
+		if plain, ok := v["@value"]; ok {
+			addJSONLDValue(item, name, plain)
+			return
+		}
+		item.add(name, jsonLDItem(v))
+	case []interface{}:
+		for _, each := range v {
+			addJSONLDValue(item, name, each)
+		}
+	}
+}
+
+// Does a depth first search for the first string value of property. This
+// handles @graph, arrays of items and nested items without having to know
+// anything about their types.
+func findJSONLDString(data interface{}, property string) (string, bool) {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		if value, ok := v[property]; ok {
+			switch value := value.(type) {
+			case string:
+				return value, true
+			case []interface{}:
+				for _, item := range value {
+					if s, ok := item.(string); ok {
+						return s, true
+					}
+				}
+			}
+		}
+		// Go randomizes map order, so sort to keep results stable.
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if s, ok := findJSONLDString(v[key], property); ok {
+				return s, true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := findJSONLDString(item, property); ok {
+				return s, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,185 @@
+package gogetter
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Finds the item the page's microdata is about and adds what we understand
+// about it to the tags, the same way we do for JSON-LD.
+func extractMicrodata(page *Page) error {
+	if item := mainSchemaItem(microdataItems(page.Doc, page.Url)); item != nil {
+		addSchemaTags(item, page)
+	}
+	return nil
+}
+
+// Reads microdata into items following
+// https://html.spec.whatwg.org/multipage/microdata.html
+type microdataParser struct {
+	pageUrl string
+	// Elements by id, for itemref.
+	ids map[string]*html.Node
+	// Where each element is in the document, since properties are listed in
+	// tree order no matter how they were found.
+	order map[*html.Node]int
+	// Items we're in the middle of reading, so an itemref loop doesn't
+	// send us around forever.
+	reading map[*html.Node]bool
+}
+
+// Reads the top level items on the page, which are the ones that aren't a
+// property of something else.
+func microdataItems(doc *goquery.Document, pageUrl string) []*schemaItem {
+	parser := &microdataParser{
+		pageUrl: pageUrl,
+		ids:     make(map[string]*html.Node),
+		order:   make(map[*html.Node]int),
+		reading: make(map[*html.Node]bool),
+	}
+	for _, root := range doc.Nodes {
+		parser.index(root)
+	}
+	var items []*schemaItem
+	doc.Find("[itemscope]:not([itemprop])").Each(func(i int, selection *goquery.Selection) {
+		items = append(items, parser.item(selection.Nodes[0]))
+	})
+	return items
+}
+
+func (p *microdataParser) index(node *html.Node) {
+	p.order[node] = len(p.order)
+	if id, ok := nodeAttr(node, "id"); ok && id != "" {
+		if _, exists := p.ids[id]; !exists {
+			p.ids[id] = node
+		}
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		p.index(child)
+	}
+}
+
+func (p *microdataParser) item(root *html.Node) *schemaItem {
+	p.reading[root] = true
+	defer delete(p.reading, root)
+	item := &schemaItem{}
+	if itemType, ok := nodeAttr(root, "itemtype"); ok {
+		for _, name := range strings.Fields(itemType) {
+			item.Types = append(item.Types, schemaName(name))
+		}
+	}
+	for _, property := range p.properties(root) {
+		itemprop, _ := nodeAttr(property, "itemprop")
+		value := p.value(property)
+		if value == nil {
+			continue
+		}
+		for _, name := range strings.Fields(itemprop) {
+			item.add(schemaName(name), value)
+		}
+	}
+	return item
+}
+
+// Finds the properties of the item at root: the elements with an itemprop
+// inside it or inside the elements it refers to with itemref, without going
+// into other items.
+func (p *microdataParser) properties(root *html.Node) []*html.Node {
+	var pending []*html.Node
+	for child := root.FirstChild; child != nil; child = child.NextSibling {
+		pending = append(pending, child)
+	}
+	if itemref, ok := nodeAttr(root, "itemref"); ok {
+		for _, id := range strings.Fields(itemref) {
+			if node, ok := p.ids[id]; ok {
+				pending = append(pending, node)
+			}
+		}
+	}
+	seen := map[*html.Node]bool{root: true}
+	var properties []*html.Node
+	for len(pending) > 0 {
+		current := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if current.Type != html.ElementNode || seen[current] {
+			continue
+		}
+		seen[current] = true
+		if _, ok := nodeAttr(current, "itemprop"); ok {
+			properties = append(properties, current)
+		}
+		if _, ok := nodeAttr(current, "itemscope"); !ok {
+			for child := current.FirstChild; child != nil; child = child.NextSibling {
+				pending = append(pending, child)
+			}
+		}
+	}
+	sort.Slice(properties, func(i, j int) bool {
+		return p.order[properties[i]] < p.order[properties[j]]
+	})
+	return properties
+}
+
+// What a property's value is depends on what kind of element it's on.
+// Returns nil for an item we're already reading.
+func (p *microdataParser) value(node *html.Node) interface{} {
+	if _, ok := nodeAttr(node, "itemscope"); ok {
+		if p.reading[node] {
+			return nil
+		}
+		return p.item(node)
+	}
+	switch node.Data {
+	case "meta":
+		content, _ := nodeAttr(node, "content")
+		return content
+	case "audio", "embed", "iframe", "img", "source", "track", "video":
+		return p.urlAttr(node, "src")
+	case "a", "area", "link":
+		return p.urlAttr(node, "href")
+	case "object":
+		return p.urlAttr(node, "data")
+	case "data", "meter":
+		value, _ := nodeAttr(node, "value")
+		return value
+	case "time":
+		if datetime, ok := nodeAttr(node, "datetime"); ok {
+			return datetime
+		}
+	}
+	return normalizeWhitespace(nodeText(node))
+}
+
+func (p *microdataParser) urlAttr(node *html.Node, name string) string {
+	value, _ := nodeAttr(node, name)
+	if value == "" || p.pageUrl == "" {
+		return value
+	}
+	if resolved, ok := resolveUrl(p.pageUrl, value); ok {
+		return resolved
+	}
+	return value
+}
+
+func nodeAttr(node *html.Node, name string) (string, bool) {
+	for _, attr := range node.Attr {
+		if attr.Namespace == "" && attr.Key == name {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+func nodeText(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+	var text strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		text.WriteString(nodeText(child))
+	}
+	return text.String()
+}
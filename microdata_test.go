@@ -0,0 +1,125 @@
+package gogetter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JustinTulloss/gogetter/wildcard"
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestMicrodataItems(t *testing.T) {
+	t.Parallel()
+	doc := `<html><body>
+		<div itemscope itemtype="https://schema.org/Product" itemref="reviews">
+			<h1 itemprop="name">Widget</h1>
+			<img itemprop="image" src="/widget.jpg" />
+			<div itemprop="brand" itemscope itemtype="https://schema.org/Brand">
+				<span itemprop="name">Acme</span>
+			</div>
+			<div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+				<data itemprop="price" value="19.99">$19.99</data>
+				<meta itemprop="priceCurrency" content="USD" />
+				<link itemprop="availability" href="https://schema.org/InStock" />
+			</div>
+		</div>
+		<div id="reviews" itemprop="aggregateRating" itemscope itemtype="https://schema.org/AggregateRating">
+			<span itemprop="ratingValue">4.5</span> from <span itemprop="reviewCount">12</span> reviews
+		</div>
+	</body></html>`
+	page, err := goquery.NewDocumentFromReader(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := microdataItems(page, "http://shop.example.com/widget")
+	if len(items) != 1 {
+		t.Fatalf("Expected 1 top level item, got %d", len(items))
+	}
+	tagged := &Page{tags: make(map[string]string)}
+	addSchemaTags(mainSchemaItem(items), tagged)
+	tags := tagged.tags
+	expected := map[string]string{
+		"schema:type":                        "product",
+		"schema:name":                        "Widget",
+		"schema:image":                       "http://shop.example.com/widget.jpg",
+		"schema:brand":                       "Acme",
+		"schema:offers:price":                "19.99",
+		"schema:offers:priceCurrency":        "USD",
+		"schema:offers:availability":         "InStock",
+		"schema:aggregateRating:ratingValue": "4.5",
+		"schema:aggregateRating:reviewCount": "12",
+	}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("%#v != %#v", tags, expected)
+	}
+}
+
+func TestMicrodataItemrefLoop(t *testing.T) {
+	t.Parallel()
+	doc := `<div id="a" itemscope itemtype="https://schema.org/Place" itemref="b">
+		<span itemprop="name">Somewhere</span>
+	</div>
+	<div id="b" itemprop="containedInPlace" itemscope itemref="a">
+		<span itemprop="name">Somewhere else</span>
+	</div>`
+	page, err := goquery.NewDocumentFromReader(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := microdataItems(page, "")
+	if len(items) != 1 || items[0].Properties["name"][0] != "Somewhere" {
+		t.Errorf("Unexpected items %#v", items)
+	}
+}
+
+func TestMicrodataPlaceCard(t *testing.T) {
+	t.Parallel()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	doc := `<html><body>
+		<div itemscope itemtype="http://schema.org/Restaurant">
+			<h1 itemprop="name">Joe's Diner</h1>
+			<div itemprop="address" itemscope itemtype="http://schema.org/PostalAddress">
+				<span itemprop="streetAddress">1 Main St</span>
+				<span itemprop="addressLocality">Springfield</span>
+			</div>
+			<div itemprop="geo" itemscope itemtype="http://schema.org/GeoCoordinates">
+				<meta itemprop="latitude" content="40.75" />
+				<meta itemprop="longitude" content="-73.98" />
+			</div>
+			<span itemprop="telephone">555-1234</span>
+		</div>
+	</body></html>`
+	result, err := scraper.ParseTags(strings.NewReader(doc), "http://example.com/joes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	latitude, longitude := 40.75, -73.98
+	expected := &wildcard.PlaceCard{
+		Card: wildcard.Card{
+			CardType: wildcard.PlaceType,
+			WebUrl:   "http://example.com/joes",
+		},
+		Place: &wildcard.Place{
+			Url: "http://example.com/joes",
+			Address: &wildcard.PostalAddress{
+				StreetAddress: "1 Main St",
+				Locality:      "Springfield",
+			},
+			Location: &wildcard.GeoCoordinates{
+				Latitude:  &latitude,
+				Longitude: &longitude,
+			},
+			PhoneNumber: "555-1234",
+			GenericMetadata: wildcard.GenericMetadata{
+				Title: "Joe's Diner",
+			},
+		},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("%#v != %#v", result, expected)
+	}
+}
@@ -0,0 +1,326 @@
+package gogetter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/JustinTulloss/gogetter/wildcard"
+)
+
+// How much of a PDF we read looking for metadata. Linearized PDFs and most
+// PDFs written by word processors keep it near the start.
+const maxPDFBytes = 512 * 1024
+
+// When the metadata isn't near the start it's usually referenced from the
+// trailer at the very end, so that's the next place we look.
+const pdfTailBytes = 64 * 1024
+
+var (
+	pdfObject     = regexp.MustCompile(`(?s)(\d+)\s+(\d+)\s+obj\b(.*?)\bendobj`)
+	pdfInfoRef    = regexp.MustCompile(`/Info\s+(\d+)\s+(\d+)\s+R`)
+	pdfPagesType  = regexp.MustCompile(`/Type\s*/Pages\b`)
+	pdfPageCount  = regexp.MustCompile(`/Count\s+(\d+)`)
+	pdfLinearized = regexp.MustCompile(`/Linearized\b[^>]*?/N\s+(\d+)`)
+	pdfReference  = regexp.MustCompile(`^(\d+)\s+(\d+)\s+R`)
+)
+
+// What we could find out about a PDF from its document information
+// dictionary and XMP metadata.
+type pdfMetadata struct {
+	Title        string
+	Author       string
+	Subject      string
+	Keywords     []string
+	CreationDate *time.Time
+	PageCount    int
+}
+
+// Builds a document card for the PDF being read from body, which is the
+// response for url. We only read as much of the file as we need to; if the
+// metadata isn't near the start we ask the server for the end instead of
+// downloading everything in between.
+func (s *Scraper) scrapePDF(body io.Reader, url string) (*wildcard.DocumentCard, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(body, maxPDFBytes))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == maxPDFBytes && !pdfInfoRef.Match(data) {
+		tail, err := s.fetchRange(url, fmt.Sprintf("bytes=-%d", pdfTailBytes), pdfTailBytes)
+		if err == nil {
+			data = append(append(data, '\n'), tail...)
+		}
+	}
+	metadata := readPDFMetadata(data)
+	card := wildcard.NewDocumentCard(url, url)
+	card.Document.ContentType = "application/pdf"
+	card.Document.Title = metadata.Title
+	card.Document.Author = metadata.Author
+	card.Document.Subject = metadata.Subject
+	card.Document.Keywords = metadata.Keywords
+	card.Document.PublicationDate = metadata.CreationDate
+	card.Document.PageCount = metadata.PageCount
+	if card.Document.Title == "" {
+		// The file name is better than nothing, and often pretty good.
+		card.Document.Title = path.Base(strings.SplitN(url, "?", 2)[0])
+	}
+	return card, nil
+}
+
+// Pulls metadata out of however much of a PDF we have. The document
+// information dictionary takes precedence, and XMP fills in whatever it
+// doesn't have. Objects that are compressed inside object streams are out
+// of reach, but XMP is almost always stored uncompressed so there's usually
+// something to go on.
+func readPDFMetadata(data []byte) *pdfMetadata {
+	metadata := &pdfMetadata{}
+	objects := make(map[string][]byte)
+	for _, match := range pdfObject.FindAllSubmatch(data, -1) {
+		objects[string(match[1])+" "+string(match[2])] = match[3]
+		if pdfPagesType.Match(match[3]) {
+			if count := pdfPageCount.FindSubmatch(match[3]); count != nil {
+				// The root of the page tree has the biggest count.
+				n, _ := strconv.Atoi(string(count[1]))
+				if n > metadata.PageCount {
+					metadata.PageCount = n
+				}
+			}
+		}
+	}
+	if metadata.PageCount == 0 {
+		if linearized := pdfLinearized.FindSubmatch(data); linearized != nil {
+			metadata.PageCount, _ = strconv.Atoi(string(linearized[1]))
+		}
+	}
+
+	// There can be more than one trailer when a file has been updated, and
+	// the last one is the current one.
+	refs := pdfInfoRef.FindAllSubmatch(data, -1)
+	if len(refs) > 0 {
+		ref := refs[len(refs)-1]
+		info := objects[string(ref[1])+" "+string(ref[2])]
+		if info != nil {
+			metadata.Title = pdfInfoString(info, "Title", objects)
+			metadata.Author = pdfInfoString(info, "Author", objects)
+			metadata.Subject = pdfInfoString(info, "Subject", objects)
+			metadata.Keywords = splitKeywords(pdfInfoString(info, "Keywords", objects))
+			if t, ok := parsePDFDate(pdfInfoString(info, "CreationDate", objects)); ok {
+				metadata.CreationDate = &t
+			}
+		}
+	}
+
+	if packet := findXMPPacket(data); packet != nil {
+		xmp := readXMP(packet)
+		if metadata.Title == "" {
+			metadata.Title = firstValue(xmp, "dc:title")
+		}
+		if metadata.Author == "" {
+			metadata.Author = strings.Join(xmp["dc:creator"], ", ")
+		}
+		if metadata.Subject == "" {
+			metadata.Subject = firstValue(xmp, "dc:description")
+		}
+		if len(metadata.Keywords) == 0 {
+			metadata.Keywords = splitKeywords(firstValue(xmp, "pdf:Keywords"))
+		}
+		if len(metadata.Keywords) == 0 {
+			metadata.Keywords = xmp["dc:subject"]
+		}
+		if metadata.CreationDate == nil {
+			if t, ok := parseDate(firstValue(xmp, "xmp:CreateDate")); ok {
+				metadata.CreationDate = &t
+			}
+		}
+	}
+	return metadata
+}
+
+// Finds the string value of key in a dictionary, following an indirect
+// reference to another object if that's what's there.
+func pdfInfoString(dict []byte, key string, objects map[string][]byte) string {
+	name := []byte("/" + key)
+	for start := 0; ; {
+		i := bytes.Index(dict[start:], name)
+		if i < 0 {
+			return ""
+		}
+		value := dict[start+i+len(name):]
+		start += i + len(name)
+		// Make sure we found /Title and not /TitleSomethingElse.
+		if len(value) > 0 && !isPDFDelimiter(value[0]) {
+			continue
+		}
+		value = bytes.TrimLeft(value, " \t\r\n")
+		if ref := pdfReference.FindSubmatch(value); ref != nil {
+			value = bytes.TrimLeft(objects[string(ref[1])+" "+string(ref[2])], " \t\r\n")
+		}
+		return strings.TrimSpace(decodePDFString(value))
+	}
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte(" \t\r\n()<>[]{}/%", c) >= 0
+}
+
+// Decodes the literal or hex string at the start of data. PDF text strings
+// are either UTF-16BE with a byte order mark or PDFDocEncoding, which is
+// close enough to Latin-1 for our purposes.
+func decodePDFString(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	var raw []byte
+	switch data[0] {
+	case '(':
+		raw = decodePDFLiteral(data[1:])
+	case '<':
+		end := bytes.IndexByte(data, '>')
+		if end < 0 {
+			return ""
+		}
+		raw = decodePDFHex(data[1:end])
+	default:
+		return ""
+	}
+	if len(raw) >= 2 && raw[0] == 0xfe && raw[1] == 0xff {
+		units := make([]uint16, 0, len(raw)/2)
+		for i := 2; i+1 < len(raw); i += 2 {
+			units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	if len(raw) >= 3 && raw[0] == 0xef && raw[1] == 0xbb && raw[2] == 0xbf {
+		return string(raw[3:])
+	}
+	return latin1(raw)
+}
+
+// Reads a literal string up to its closing paren, which might be a while
+// since balanced parens are allowed inside without escaping.
+func decodePDFLiteral(data []byte) []byte {
+	var out []byte
+	depth := 0
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return out
+			}
+			depth--
+		case '\\':
+			i++
+			if i >= len(data) {
+				return out
+			}
+			switch e := data[i]; e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r', '\n':
+				// A backslash at the end of a line continues the string.
+				if e == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+					i++
+				}
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					octal := 0
+					for j := 0; j < 3 && i < len(data) && data[i] >= '0' && data[i] <= '7'; j++ {
+						octal = octal*8 + int(data[i]-'0')
+						i++
+					}
+					i--
+					c = byte(octal)
+				} else {
+					c = e
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func decodePDFHex(data []byte) []byte {
+	var digits []byte
+	for _, c := range data {
+		if strings.IndexByte("0123456789abcdefABCDEF", c) >= 0 {
+			digits = append(digits, c)
+		}
+	}
+	// A missing final digit is assumed to be zero.
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	for i := range out {
+		n, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		out[i] = byte(n)
+	}
+	return out
+}
+
+// PDF dates look like D:20150304100000-08'00', where everything after the
+// year is optional.
+func parsePDFDate(value string) (time.Time, bool) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "D:")
+	value = strings.Replace(value, "'", "", -1)
+	value = strings.TrimSuffix(value, "Z0000")
+	value = strings.TrimSuffix(value, "Z00")
+	for _, layout := range []string{
+		"20060102150405-0700",
+		"20060102150405Z",
+		"20060102150405",
+		"200601021504-0700",
+		"200601021504",
+		"2006010215",
+		"20060102",
+		"200601",
+		"2006",
+	} {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t, true
+		}
+	}
+	return parseDate(value)
+}
+
+// Keywords come separated by commas or semicolons depending on who wrote
+// the file.
+func splitKeywords(value string) []string {
+	var keywords []string
+	for _, keyword := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';'
+	}) {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			keywords = append(keywords, keyword)
+		}
+	}
+	return keywords
+}
+
+func firstValue(values map[string][]string, key string) string {
+	if len(values[key]) == 0 {
+		return ""
+	}
+	return values[key][0]
+}
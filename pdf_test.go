@@ -0,0 +1,57 @@
+package gogetter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testPDF = `%PDF-1.4
+1 0 obj
+<< /Type /Catalog /Pages 2 0 R /Metadata 5 0 R >>
+endobj
+2 0 obj
+<< /Type /Pages /Kids [3 0 R] /Count 12 >>
+endobj
+3 0 obj
+<< /Type /Page /Parent 2 0 R >>
+endobj
+4 0 obj
+<< /Title (A Study of \(Nested\) Things)
+   /Author <FEFF004A006F0073006900650020004D0061007200ED006E>
+   /Keywords (graphs; trees, forests)
+   /CreationDate (D:20150304100000-08'00') >>
+endobj
+5 0 obj
+<< /Type /Metadata /Subtype /XML /Length 400 >>
+stream
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">
+   <dc:title><rdf:Alt><rdf:li xml:lang="x-default">XMP title</rdf:li></rdf:Alt></dc:title>
+   <dc:description><rdf:Alt><rdf:li xml:lang="x-default">What it is about</rdf:li></rdf:Alt></dc:description>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+endstream
+endobj
+trailer
+<< /Root 1 0 R /Info 4 0 R >>
+%%EOF`
+
+func TestReadPDFMetadata(t *testing.T) {
+	t.Parallel()
+	created := time.Date(2015, 3, 4, 10, 0, 0, 0, time.FixedZone("", -8*60*60))
+	expected := &pdfMetadata{
+		Title:        "A Study of (Nested) Things",
+		Author:       "Josie Marín",
+		Subject:      "What it is about",
+		Keywords:     []string{"graphs", "trees", "forests"},
+		CreationDate: &created,
+		PageCount:    12,
+	}
+	metadata := readPDFMetadata([]byte(testPDF))
+	if !reflect.DeepEqual(metadata, expected) {
+		t.Errorf("%#v != %#v", metadata, expected)
+	}
+}
@@ -0,0 +1,103 @@
+package gogetter
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Namespaces we know, by the prefix we use for them. Pages can declare any
+// prefix they like for these, so we go by the namespace rather than the name.
+var knownNamespaces = map[string]string{
+	"http://purl.org/dc/elements/1.1/": "dc",
+	"http://purl.org/dc/terms/":        "dcterms",
+	"http://schema.org/":               "schema",
+	"https://schema.org/":              "schema",
+	"http://ogp.me/ns#":                "og",
+	"http://ogp.me/ns/article#":        "article",
+	"http://ogp.me/ns/book#":           "book",
+	"http://ogp.me/ns/fb#":             "fb",
+	"http://ogp.me/ns/music#":          "music",
+	"http://ogp.me/ns/profile#":        "profile",
+	"http://ogp.me/ns/video#":          "video",
+	"http://ogp.me/ns/website#":        "website",
+}
+
+// Reads the prefixes a page declares with the RDFa prefix attribute on <html>
+// or <head>, like prefix="og: http://ogp.me/ns# myapp: http://example.com/ns#",
+// or the older xmlns:og="http://ogp.me/ns#". Returns the prefix each one
+// should be known by, which is its own name unless it's a namespace we know.
+func declaredPrefixes(doc *goquery.Document) map[string]string {
+	prefixes := make(map[string]string)
+	declare := func(prefix, namespace string) {
+		prefix = strings.ToLower(prefix)
+		if prefix == "" || strings.ContainsAny(prefix, `"'[]`) {
+			return
+		}
+		if known, ok := knownNamespaces[namespace]; ok {
+			prefixes[prefix] = known
+		} else {
+			prefixes[prefix] = prefix
+		}
+	}
+	doc.Find("html, head").Each(func(i int, selection *goquery.Selection) {
+		for _, attr := range selection.Nodes[0].Attr {
+			if strings.HasPrefix(attr.Key, "xmlns:") {
+				declare(strings.TrimPrefix(attr.Key, "xmlns:"), strings.TrimSpace(attr.Val))
+			}
+		}
+		declaration, ok := selection.Attr("prefix")
+		if !ok {
+			return
+		}
+		fields := strings.Fields(declaration)
+		for j := 0; j+1 < len(fields); j++ {
+			if strings.HasSuffix(fields[j], ":") {
+				declare(strings.TrimSuffix(fields[j], ":"), fields[j+1])
+				j++
+			}
+		}
+	})
+	return prefixes
+}
+
+// Sets up a page for the extractors with this scraper's prefixes and aliases,
+// plus whatever prefixes the page declares.
+func (s *Scraper) newPage(doc *goquery.Document, webUrl string) *Page {
+	page := &Page{
+		Doc:     doc,
+		Url:     webUrl,
+		tags:    make(map[string]string),
+		aliases: s.aliases,
+	}
+	seen := make(map[string]bool)
+	for _, prefix := range s.prefixes {
+		if !seen[prefix] {
+			seen[prefix] = true
+			page.prefixes = append(page.prefixes, prefix)
+		}
+	}
+	declared := declaredPrefixes(doc)
+	names := make([]string, 0, len(declared))
+	for prefix := range declared {
+		names = append(names, prefix)
+	}
+	// Go randomizes map order, and the order we look for prefixes in decides
+	// which tag wins.
+	sort.Strings(names)
+	for _, prefix := range names {
+		known := declared[prefix]
+		if prefix != known {
+			if page.renamedPrefixes == nil {
+				page.renamedPrefixes = make(map[string]string)
+			}
+			page.renamedPrefixes[prefix] = known
+		}
+		if !seen[prefix] {
+			seen[prefix] = true
+			page.prefixes = append(page.prefixes, prefix)
+		}
+	}
+	return page
+}
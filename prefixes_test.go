@@ -0,0 +1,51 @@
+package gogetter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JustinTulloss/gogetter/wildcard"
+)
+
+func TestDeclaredPrefixesAndAliases(t *testing.T) {
+	t.Parallel()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	scraper.AddAliases("og:description", "myapp:summary")
+	scraper.AddPrefixes("house")
+	scraper.SetAliases("og:site_name", "house:brand")
+	doc := `<html prefix="ogp: http://ogp.me/ns# myapp: http://example.com/ns#">
+		<head>
+			<meta property="ogp:title" content="Declared prefix" />
+			<meta property="myapp:summary" content="Custom namespace" />
+			<meta name="house:brand" content="House brand" />
+			<meta name="cre" content="Not the site name anymore" />
+		</head>
+	</html>`
+	expected := &wildcard.LinkCard{
+		Card: wildcard.Card{
+			CardType: wildcard.LinkType,
+		},
+		Target: &wildcard.LinkTarget{
+			Description: "Custom namespace",
+			GenericMetadata: wildcard.GenericMetadata{
+				Title:  "Declared prefix",
+				Source: "House brand",
+			},
+		},
+	}
+	result, err := scraper.ParseTags(strings.NewReader(doc), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("%#v != %#v", result, expected)
+	}
+	// Other scrapers keep the default aliases.
+	if reflect.DeepEqual(tagAliases["og:site_name"], []string{"house:brand"}) {
+		t.Errorf("SetAliases changed the defaults")
+	}
+}
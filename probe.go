@@ -0,0 +1,222 @@
+package gogetter
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/JustinTulloss/gogetter/wildcard"
+)
+
+// How much of an image we fetch to figure out what it is. JPEGs with big
+// EXIF blocks can push the frame header out pretty far, so this is more
+// than the other formats need.
+const maxProbeBytes = 64 * 1024
+
+var errUnknownImageFormat = errors.New("Unknown image format")
+
+// What we learned about an image from the start of its file.
+type imageInfo struct {
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// Fetches the start of the image at imageUrl and figures out its type and
+// dimensions from the header. Returns an error if the image can't be
+// loaded or isn't an image. Images in a format we can't measure, or whose
+// size is further in than we look, come back without dimensions.
+func (s *Scraper) probeImage(ctx context.Context, imageUrl string) (*imageInfo, error) {
+	req, err := s.buildRequest(imageUrl)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", maxProbeBytes-1))
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("Could not fetch %s (%d)", imageUrl, resp.StatusCode)
+	}
+	contentType, body, err := sniffContentType(resp.Header.Get("Content-Type"), resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	// Servers that ignore the range send the whole thing, so we stop
+	// reading once we have enough.
+	data, err := ioutil.ReadAll(io.LimitReader(body, maxProbeBytes))
+	if err != nil {
+		return nil, err
+	}
+	info, err := decodeImageHeader(data)
+	if err == nil {
+		return info, nil
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, fmt.Errorf("%s is %s, not an image", imageUrl, contentType)
+	}
+	return &imageInfo{ContentType: contentType}, nil
+}
+
+// Figures out the type and size of an image from the first few bytes of it.
+func decodeImageHeader(data []byte) (*imageInfo, error) {
+	switch {
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return decodeWebPHeader(data)
+	case len(data) >= 12 && string(data[4:8]) == "ftyp":
+		return decodeAVIFHeader(data)
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errUnknownImageFormat
+	}
+	return &imageInfo{
+		ContentType: "image/" + format,
+		Width:       config.Width,
+		Height:      config.Height,
+	}, nil
+}
+
+// WebP comes in three flavors, each of which keeps its size somewhere
+// different: https://developers.google.com/speed/webp/docs/riff_container
+func decodeWebPHeader(data []byte) (*imageInfo, error) {
+	if len(data) < 30 {
+		return nil, errUnknownImageFormat
+	}
+	info := &imageInfo{ContentType: "image/webp"}
+	switch string(data[12:16]) {
+	case "VP8 ":
+		// Lossy, the frame header follows a 3 byte start code.
+		if data[23] != 0x9d || data[24] != 0x01 || data[25] != 0x2a {
+			return nil, errUnknownImageFormat
+		}
+		info.Width = int(binary.LittleEndian.Uint16(data[26:28]) & 0x3fff)
+		info.Height = int(binary.LittleEndian.Uint16(data[28:30]) & 0x3fff)
+	case "VP8L":
+		// Lossless, 14 bits each for width and height minus one.
+		if data[20] != 0x2f {
+			return nil, errUnknownImageFormat
+		}
+		bits := binary.LittleEndian.Uint32(data[21:25])
+		info.Width = int(bits&0x3fff) + 1
+		info.Height = int((bits>>14)&0x3fff) + 1
+	case "VP8X":
+		// Extended, 24 bits each for canvas width and height minus one.
+		info.Width = int(uint32(data[24])|uint32(data[25])<<8|uint32(data[26])<<16) + 1
+		info.Height = int(uint32(data[27])|uint32(data[28])<<8|uint32(data[29])<<16) + 1
+	default:
+		return nil, errUnknownImageFormat
+	}
+	return info, nil
+}
+
+// AVIF is an ISO base media file. Rather than walking the whole box tree we
+// look for the first image spatial extents property, which is the size of
+// the primary image in every file we've seen.
+func decodeAVIFHeader(data []byte) (*imageInfo, error) {
+	size := int(binary.BigEndian.Uint32(data[0:4]))
+	if size < 16 || size > len(data) {
+		return nil, errUnknownImageFormat
+	}
+	brands := string(data[8:size])
+	if !strings.Contains(brands, "avif") && !strings.Contains(brands, "avis") {
+		return nil, errUnknownImageFormat
+	}
+	ispe := bytes.Index(data, []byte("ispe"))
+	// The fourcc is followed by a version and flags, then width and height.
+	if ispe < 0 || ispe+16 > len(data) {
+		return nil, errUnknownImageFormat
+	}
+	return &imageInfo{
+		ContentType: "image/avif",
+		Width:       int(binary.BigEndian.Uint32(data[ispe+8 : ispe+12])),
+		Height:      int(binary.BigEndian.Uint32(data[ispe+12 : ispe+16])),
+	}, nil
+}
+
+// Checks the images on a card actually exist and fills in what we can about
+// them. Images that can't be loaded are taken off the card since they'd
+// only show up broken.
+func (s *Scraper) probeCardImages(card wildcard.Wildcard) {
+	metadata := card.Metadata()
+	if metadata != nil && metadata.Image != nil && metadata.Image.ImageUrl != "" {
+		info, err := s.probeImage(context.Background(), metadata.Image.ImageUrl)
+		if err != nil {
+			addWarning(card, fmt.Sprintf("Removed image that could not be loaded: %s", err))
+			metadata.Image = nil
+		} else {
+			// What the page said is all we have for images we can't
+			// measure.
+			if info.Width > 0 && info.Height > 0 {
+				metadata.Image.Width = info.Width
+				metadata.Image.Height = info.Height
+			}
+			metadata.Image.ImageContentType = info.ContentType
+		}
+	}
+	if video, ok := card.(*wildcard.VideoCard); ok && video.Media != nil && video.Media.PosterImageUrl != "" {
+		if _, err := s.probeImage(context.Background(), video.Media.PosterImageUrl); err != nil {
+			addWarning(card, fmt.Sprintf("Removed poster image that could not be loaded: %s", err))
+			video.Media.PosterImageUrl = ""
+		}
+	}
+}
+
+// Changes the urls of images on cards, like to send them through an image
+// proxy so the people looking at the cards don't load them from the
+// publisher.
+type ImageUrlRewriter interface {
+	RewriteImageUrl(imageUrl string) string
+}
+
+// Has ScrapeTags rewrite every image url on the cards it makes. It's the last
+// thing done to a card, so images are checked against the invalid card
+// policy and probed with the urls the page gave. Pass nil to leave them
+// alone again.
+func (s *Scraper) SetImageUrlRewriter(rewriter ImageUrlRewriter) {
+	s.imageUrlRewriter = rewriter
+}
+
+func (s *Scraper) rewriteCardImages(card wildcard.Wildcard) {
+	if s.imageUrlRewriter == nil {
+		return
+	}
+	rewrite := func(imageUrl *string) {
+		if *imageUrl != "" {
+			*imageUrl = s.imageUrlRewriter.RewriteImageUrl(*imageUrl)
+		}
+	}
+	if metadata := card.Metadata(); metadata != nil {
+		if metadata.Image != nil {
+			rewrite(&metadata.Image.ImageUrl)
+		}
+		rewrite(&metadata.SourceIcon)
+	}
+	switch c := card.(type) {
+	case *wildcard.ImageCard:
+		if c.Media != nil {
+			rewrite(&c.Media.ImageUrl)
+		}
+	case *wildcard.VideoCard:
+		if c.Media != nil {
+			rewrite(&c.Media.PosterImageUrl)
+		}
+	}
+}
+
+func addWarning(card wildcard.Wildcard, warning string) {
+	base := card.BaseCard()
+	base.Warnings = append(base.Warnings, warning)
+}
@@ -0,0 +1,212 @@
+package gogetter
+
+import (
+	"bytes"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/JustinTulloss/gogetter/wildcard"
+)
+
+func TestDecodeImageHeader(t *testing.T) {
+	t.Parallel()
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	var pngData, jpegData, gifData bytes.Buffer
+	png.Encode(&pngData, img)
+	jpeg.Encode(&jpegData, img, nil)
+	gif.Encode(&gifData, img, nil)
+	webpData := []byte("RIFF\x00\x00\x00\x00WEBPVP8X\x0a\x00\x00\x00\x00\x00\x00\x00\x3f\x00\x00\x2f\x00\x00")
+	avifData := append([]byte("\x00\x00\x00\x14ftypavif\x00\x00\x00\x00avif"),
+		[]byte("\x00\x00\x00\x14ispe\x00\x00\x00\x00\x00\x00\x00\x40\x00\x00\x00\x30")...)
+	cases := []struct {
+		name string
+		data []byte
+		info *imageInfo
+	}{
+		{"png", pngData.Bytes(), &imageInfo{"image/png", 64, 48}},
+		{"jpeg", jpegData.Bytes(), &imageInfo{"image/jpeg", 64, 48}},
+		{"gif", gifData.Bytes(), &imageInfo{"image/gif", 64, 48}},
+		{"webp", webpData, &imageInfo{"image/webp", 64, 48}},
+		{"avif", avifData, &imageInfo{"image/avif", 64, 48}},
+	}
+	for _, c := range cases {
+		info, err := decodeImageHeader(c.data)
+		if err != nil {
+			t.Errorf("%s: %s", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(info, c.info) {
+			t.Errorf("%s: %#v != %#v", c.name, info, c.info)
+		}
+	}
+	if _, err := decodeImageHeader([]byte("<html>not an image</html>")); err == nil {
+		t.Errorf("Expected html not to decode as an image")
+	}
+}
+
+func TestProbeCardImages(t *testing.T) {
+	t.Parallel()
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	var pngData bytes.Buffer
+	png.Encode(&pngData, img)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(pngData.Bytes())
+		case "/image.svg":
+			w.Header().Set("Content-Type", "image/svg+xml")
+			w.Write([]byte(`<svg xmlns="http://www.w3.org/2000/svg" width="600" height="400"></svg>`))
+		case "/image.html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body>Not an image</body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	scraper.SetClient(server.Client())
+
+	cases := []struct {
+		path  string
+		image *wildcard.ImageDetails
+	}{
+		{"/image.png", &wildcard.ImageDetails{Width: 64, Height: 48, ImageContentType: "image/png"}},
+		// We can't measure SVGs, but they load fine, so they keep the
+		// size the page gave them.
+		{"/image.svg", &wildcard.ImageDetails{Width: 1200, Height: 630, ImageContentType: "image/svg+xml"}},
+		{"/image.html", nil},
+		{"/missing.png", nil},
+	}
+	for _, c := range cases {
+		card := wildcard.NewLinkCard(server.URL, server.URL)
+		card.Target.Image = &wildcard.ImageDetails{ImageUrl: server.URL + c.path, Width: 1200, Height: 630}
+		scraper.probeCardImages(card)
+		if c.image != nil {
+			c.image.ImageUrl = server.URL + c.path
+		}
+		if !reflect.DeepEqual(card.Target.Image, c.image) {
+			t.Errorf("%s: %#v != %#v", c.path, card.Target.Image, c.image)
+		}
+		if removed := len(card.Warnings) > 0; removed != (c.image == nil) {
+			t.Errorf("%s: unexpected warnings %#v", c.path, card.Warnings)
+		}
+	}
+}
+
+// Sends every image through a made up proxy.
+type prefixRewriter string
+
+func (r prefixRewriter) RewriteImageUrl(imageUrl string) string {
+	return string(r) + imageUrl
+}
+
+func TestRewriteCardImages(t *testing.T) {
+	t.Parallel()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	link := wildcard.NewLinkCard("http://example.com/a", "http://example.com/a")
+	link.Target.Image = &wildcard.ImageDetails{ImageUrl: "http://example.com/a.png"}
+	link.Target.SourceIcon = "http://example.com/favicon.ico"
+	video := wildcard.NewVideoCard("http://example.com/v")
+	video.Media.PosterImageUrl = "http://example.com/poster.jpg"
+	photo := wildcard.NewImageCard("http://example.com/i.png", "http://example.com/i.png")
+
+	// Nothing happens until there's a rewriter.
+	scraper.rewriteCardImages(link)
+	if link.Target.Image.ImageUrl != "http://example.com/a.png" {
+		t.Errorf("Image was rewritten without a rewriter: %q", link.Target.Image.ImageUrl)
+	}
+
+	scraper.SetImageUrlRewriter(prefixRewriter("https://proxy.example.com/?url="))
+	for _, card := range []wildcard.Wildcard{link, video, photo} {
+		scraper.rewriteCardImages(card)
+	}
+	rewritten := map[string]string{
+		"link image":  link.Target.Image.ImageUrl,
+		"source icon": link.Target.SourceIcon,
+		"poster":      video.Media.PosterImageUrl,
+		"image":       photo.Media.ImageUrl,
+	}
+	expected := map[string]string{
+		"link image":  "https://proxy.example.com/?url=http://example.com/a.png",
+		"source icon": "https://proxy.example.com/?url=http://example.com/favicon.ico",
+		"poster":      "https://proxy.example.com/?url=http://example.com/poster.jpg",
+		"image":       "https://proxy.example.com/?url=http://example.com/i.png",
+	}
+	if !reflect.DeepEqual(rewritten, expected) {
+		t.Errorf("%#v != %#v", rewritten, expected)
+	}
+	// Empty urls stay empty rather than pointing at the proxy.
+	if video.Media.Image != nil && video.Media.Image.ImageUrl != "" {
+		t.Errorf("Unexpected video image %q", video.Media.Image.ImageUrl)
+	}
+}
+
+func TestImageUrlRewriter(t *testing.T) {
+	t.Parallel()
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	var pngData bytes.Buffer
+	png.Encode(&pngData, img)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/story":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head>
+				<meta property="og:title" content="A story" />
+				<meta property="og:image" content="http://example.com/a.png" />
+			</head></html>`))
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(pngData.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	scraper.SetClient(server.Client())
+	scraper.SetInvalidCardPolicy(RepairInvalidCards)
+	scraper.SetImageUrlRewriter(prefixRewriter("https://proxy.example.com/?url="))
+
+	// Pages and images go through the same steps, so both are checked with
+	// the original url and rewritten afterwards.
+	result, err := scraper.ScrapeTags(server.URL + "/story")
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, ok := result.(*wildcard.LinkCard)
+	if !ok {
+		t.Fatalf("Expected a link card, got %#v", result)
+	}
+	if link.Target.Image == nil || link.Target.Image.ImageUrl != "https://proxy.example.com/?url=http://example.com/a.png" {
+		t.Errorf("Page image wasn't rewritten: %#v", link.Target.Image)
+	}
+
+	result, err = scraper.ScrapeTags(server.URL + "/image.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	imageCard, ok := result.(*wildcard.ImageCard)
+	if !ok {
+		t.Fatalf("Expected an image card, got %#v", result)
+	}
+	if imageCard.Media.ImageUrl != "https://proxy.example.com/?url="+server.URL+"/image.png" {
+		t.Errorf("Image wasn't rewritten: %q", imageCard.Media.ImageUrl)
+	}
+}
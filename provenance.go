@@ -0,0 +1,214 @@
+package gogetter
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/JustinTulloss/gogetter/wildcard"
+)
+
+// Returns a copy of the scraper that records where every field on the cards
+// it makes came from, in Card.Provenance. It's meant for debugging single
+// requests, since it makes parsing a bit slower.
+func (s *Scraper) WithProvenance() *Scraper {
+	copy := *s
+	copy.shouldRecordProvenance = true
+	return &copy
+}
+
+// Keeps track of what each extractor did while ParseTags runs. A nil
+// recorder records nothing, so callers don't have to check.
+type provenanceRecorder struct {
+	aliases map[string][]string
+	// Which extractor found each tag
+	tagExtractors map[string]string
+	// The tags before aliases were resolved, so we can tell which ones lost
+	tags       map[string]string
+	provenance map[string]*wildcard.Provenance
+}
+
+func newProvenanceRecorder(aliases map[string][]string) *provenanceRecorder {
+	return &provenanceRecorder{
+		aliases:       aliases,
+		tagExtractors: make(map[string]string),
+		provenance:    make(map[string]*wildcard.Provenance),
+	}
+}
+
+// Runs extractor, crediting it with the tags that weren't there before.
+func (r *provenanceRecorder) extract(extractor Extractor, page *Page) error {
+	if r == nil {
+		return extractor.Extract(page)
+	}
+	before := make(map[string]bool, len(page.tags))
+	for tag := range page.tags {
+		before[tag] = true
+	}
+	err := extractor.Extract(page)
+	name := extractorName(extractor)
+	for tag := range page.tags {
+		if !before[tag] {
+			r.tagExtractors[tag] = name
+		}
+	}
+	return err
+}
+
+// Remembers the tags as they were before the card is made out of them.
+func (r *provenanceRecorder) snapshot(tags map[string]string) {
+	if r == nil {
+		return
+	}
+	r.tags = make(map[string]string, len(tags))
+	for tag, value := range tags {
+		r.tags[tag] = value
+	}
+}
+
+// Works out which tag filled in each field on the freshly made card, and
+// which tags it beat.
+func (r *provenanceRecorder) decoded(card wildcard.Wildcard) {
+	if r == nil {
+		return
+	}
+	walkCardFields(reflect.ValueOf(card).Elem(), "", func(path, tag string, field reflect.Value) {
+		if tag == "" {
+			return
+		}
+		candidates := append([]string{tag}, r.aliases[tag]...)
+		var provenance *wildcard.Provenance
+		for _, candidate := range candidates {
+			value, ok := r.tags[candidate]
+			if !ok {
+				continue
+			}
+			if provenance == nil {
+				provenance = &wildcard.Provenance{Tag: candidate, Extractor: r.tagExtractors[candidate]}
+				continue
+			}
+			provenance.Competing = append(provenance.Competing, wildcard.CompetingValue{
+				Tag:       candidate,
+				Value:     value,
+				Extractor: r.tagExtractors[candidate],
+			})
+		}
+		if provenance != nil {
+			r.provenance[path] = provenance
+		}
+	})
+}
+
+// Runs extractor, crediting it with the fields that weren't filled in
+// before.
+func (r *provenanceRecorder) extractCard(extractor CardExtractor, page *Page, card wildcard.Wildcard) error {
+	if r == nil {
+		return extractor.ExtractCard(page, card)
+	}
+	before := make(map[string]bool)
+	walkCardFields(reflect.ValueOf(card).Elem(), "", func(path, tag string, field reflect.Value) {
+		before[path] = true
+	})
+	err := extractor.ExtractCard(page, card)
+	name := extractorName(extractor)
+	walkCardFields(reflect.ValueOf(card).Elem(), "", func(path, tag string, field reflect.Value) {
+		if !before[path] {
+			r.provenance[path] = &wildcard.Provenance{Extractor: name}
+		}
+	})
+	return err
+}
+
+func (r *provenanceRecorder) finish(card wildcard.Wildcard) {
+	if r == nil || len(r.provenance) == 0 {
+		return
+	}
+	card.BaseCard().Provenance = r.provenance
+}
+
+// Drops provenance for fields that aren't on the card anymore, like an image
+// that was taken off after it couldn't be loaded.
+func pruneProvenance(card wildcard.Wildcard) {
+	base := card.BaseCard()
+	if base.Provenance == nil {
+		return
+	}
+	filled := make(map[string]bool, len(base.Provenance))
+	walkCardFields(reflect.ValueOf(card).Elem(), "", func(path, tag string, field reflect.Value) {
+		filled[path] = true
+	})
+	for path := range base.Provenance {
+		if !filled[path] {
+			delete(base.Provenance, path)
+		}
+	}
+	if len(base.Provenance) == 0 {
+		base.Provenance = nil
+	}
+}
+
+// Calls fn for every field of value that's filled in, with where it is in the
+// JSON and the tag it's decoded from, if any. Structs are looked inside;
+// everything else, including lists, counts as a single field. The fields
+// every card has are skipped.
+func walkCardFields(value reflect.Value, path string, fn func(path, tag string, field reflect.Value)) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		structField := value.Type().Field(i)
+		if structField.PkgPath != "" || structField.Type == reflect.TypeOf(wildcard.Card{}) {
+			continue
+		}
+		jsonName := strings.Split(structField.Tag.Get("json"), ",")[0]
+		if jsonName == "-" {
+			continue
+		}
+		tag := strings.Split(structField.Tag.Get("ogtag"), ",")[0]
+		if structField.Anonymous && jsonName == "" && field.Kind() == reflect.Struct {
+			walkCardFields(field, path, fn)
+			continue
+		}
+		if jsonName == "" {
+			jsonName = structField.Name
+		}
+		fieldPath := jsonName
+		if path != "" {
+			fieldPath = path + "." + jsonName
+		}
+		inner := field
+		if inner.Kind() == reflect.Ptr {
+			if inner.IsNil() {
+				continue
+			}
+			inner = inner.Elem()
+		}
+		if inner.Kind() == reflect.Struct && inner.Type() != timeType {
+			walkCardFields(inner, fieldPath, fn)
+			continue
+		}
+		if !field.IsZero() {
+			fn(fieldPath, tag, field)
+		}
+	}
+}
+
+// Extractors go by their type, or the name of their function for the
+// ExtractorFunc kind.
+func extractorName(extractor interface{}) string {
+	switch f := extractor.(type) {
+	case ExtractorFunc:
+		return funcName(f)
+	case CardExtractorFunc:
+		return funcName(f)
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", extractor), "*")
+}
+
+func funcName(f interface{}) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer())
+	if fn == nil {
+		return ""
+	}
+	name := fn.Name()
+	return name[strings.LastIndex(name, "/")+1:]
+}
@@ -0,0 +1,109 @@
+package gogetter
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JustinTulloss/gogetter/wildcard"
+)
+
+func TestProvenance(t *testing.T) {
+	t.Parallel()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	doc := `<html><head>
+		<title>The page title</title>
+		<meta property="og:type" content="article" />
+		<meta name="twitter:title" content="The twitter title" />
+		<meta name="twitter:description" content="A paper" />
+		<meta name="citation_title" content="The citation title" />
+		<meta name="citation_doi" content="doi:10.1000/xyz" />
+		<meta name="citation_author" content="Smith, Jane" />
+	</head><body></body></html>`
+	result, err := scraper.WithProvenance().ParseTags(strings.NewReader(doc), "http://example.com/paper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]*wildcard.Provenance{
+		"article.title": {
+			Tag:       "twitter:title",
+			Extractor: "gogetter.extractMetaTags",
+			Competing: []wildcard.CompetingValue{
+				{Tag: "citation_title", Value: "The citation title", Extractor: "gogetter.extractCitation"},
+				{Tag: "title", Value: "The page title", Extractor: "gogetter.extractTitle"},
+			},
+		},
+		"article.abstract_content": {
+			Tag:       "twitter:description",
+			Extractor: "gogetter.extractMetaTags",
+		},
+		"article.citation.doi": {
+			Tag:       "citation_doi",
+			Extractor: "gogetter.extractCitation",
+		},
+		"article.citation.authors": {
+			Extractor: "gogetter.extractCitationAuthors",
+		},
+		"article.byline": {
+			Extractor: "gogetter.extractCitationAuthors",
+		},
+	}
+	provenance := result.BaseCard().Provenance
+	if !reflect.DeepEqual(provenance, expected) {
+		t.Errorf("%#v != %#v", provenance, expected)
+	}
+}
+
+func TestNoProvenanceByDefault(t *testing.T) {
+	t.Parallel()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	doc := `<html><head><title>A title</title></head></html>`
+	result, err := scraper.ParseTags(strings.NewReader(doc), "http://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.BaseCard().Provenance != nil {
+		t.Errorf("Unexpected provenance %#v", result.BaseCard().Provenance)
+	}
+}
+
+func TestProvenanceIsPrunedAfterProbing(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/story" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `<html><head>
+			<meta property="og:title" content="A story" />
+			<meta property="og:image" content="http://%s/missing.png" />
+		</head></html>`, r.Host)
+	}))
+	defer server.Close()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	scraper.SetClient(server.Client())
+	scraper.SetProbeImages(true)
+	result, err := scraper.WithProvenance().ScrapeTags(server.URL + "/story")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provenance := result.(wildcard.Wildcard).BaseCard().Provenance
+	if _, ok := provenance["target.image.image_url"]; ok {
+		t.Errorf("Expected the broken image's provenance to be dropped, got %#v", provenance)
+	}
+	if _, ok := provenance["target.title"]; !ok {
+		t.Errorf("Expected the title's provenance to be kept, got %#v", provenance)
+	}
+}
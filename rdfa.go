@@ -0,0 +1,124 @@
+package gogetter
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Reads RDFa Lite (vocab, typeof, property and prefix) from the body of the
+// page. schema.org items are mapped the same way as JSON-LD and microdata,
+// and Dublin Core properties become the same tags as their meta tags. Open
+// graph in RDFa lives in meta tags, which we already read.
+func extractRDFa(page *Page) error {
+	parser := &rdfaParser{page: page, document: &schemaItem{}}
+	for _, root := range page.Doc.Nodes {
+		parser.walk(root, parser.document, "")
+	}
+	item := mainSchemaItem(parser.items)
+	if item == nil && len(parser.document.Properties) > 0 {
+		// Properties outside of any typeof are about the page itself.
+		item = parser.document
+	}
+	if item != nil {
+		addSchemaTags(item, page)
+	}
+	return nil
+}
+
+type rdfaParser struct {
+	page *Page
+	// The page itself, which is what properties outside of any typeof
+	// describe.
+	document *schemaItem
+	// Items that aren't the value of some other item's property.
+	items []*schemaItem
+}
+
+func (p *rdfaParser) walk(node *html.Node, subject *schemaItem, vocab string) {
+	if node.Type == html.ElementNode {
+		if value, ok := nodeAttr(node, "vocab"); ok {
+			vocab = strings.TrimSpace(value)
+		}
+		property, hasProperty := nodeAttr(node, "property")
+		if node.Data == "meta" && node.Parent != nil && node.Parent.Data == "head" {
+			// Meta tags in the head are handled with the rest of the
+			// meta tags.
+			hasProperty = false
+		}
+		if typeOf, ok := nodeAttr(node, "typeof"); ok {
+			item := &schemaItem{}
+			for _, name := range strings.Fields(typeOf) {
+				if vocabulary, local := p.resolve(name, vocab); vocabulary == "schema" {
+					item.Types = append(item.Types, local)
+				}
+			}
+			if hasProperty {
+				p.add(subject, property, vocab, item)
+			} else {
+				p.items = append(p.items, item)
+			}
+			subject = item
+		} else if hasProperty {
+			p.add(subject, property, vocab, p.value(node))
+		}
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		p.walk(child, subject, vocab)
+	}
+}
+
+// Adds value to subject under each of the names in property we understand.
+func (p *rdfaParser) add(subject *schemaItem, property, vocab string, value interface{}) {
+	for _, name := range strings.Fields(property) {
+		vocabulary, local := p.resolve(name, vocab)
+		switch vocabulary {
+		case "schema":
+			subject.add(local, value)
+		case "dc", "dcterms":
+			// Dublin Core is always about the page.
+			if text, ok := value.(string); ok {
+				addDublinCoreTag(p.page, vocabulary+":"+strings.ToLower(local), strings.TrimSpace(text))
+			}
+		}
+	}
+}
+
+// Works out which vocabulary a type or property name is from. Names can be
+// full urls, use a prefix like dc:title, or be plain names from the vocab
+// in effect.
+func (p *rdfaParser) resolve(name, vocab string) (string, string) {
+	for namespace, prefix := range knownNamespaces {
+		if strings.HasPrefix(name, namespace) {
+			return prefix, strings.TrimPrefix(name, namespace)
+		}
+	}
+	if parts := strings.SplitN(p.page.TagName(name), ":", 2); len(parts) == 2 {
+		return strings.ToLower(parts[0]), parts[1]
+	}
+	if prefix, ok := knownNamespaces[vocab]; ok {
+		return prefix, name
+	}
+	return "", name
+}
+
+// What a property's value is depends on the element, much like microdata.
+func (p *rdfaParser) value(node *html.Node) interface{} {
+	if content, ok := nodeAttr(node, "content"); ok {
+		return content
+	}
+	for _, name := range []string{"resource", "href", "src"} {
+		if value, ok := nodeAttr(node, name); ok {
+			if resolved, ok := resolveUrl(p.page.Url, value); ok && p.page.Url != "" {
+				return resolved
+			}
+			return value
+		}
+	}
+	if node.Data == "time" {
+		if datetime, ok := nodeAttr(node, "datetime"); ok {
+			return datetime
+		}
+	}
+	return normalizeWhitespace(nodeText(node))
+}
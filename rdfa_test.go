@@ -0,0 +1,92 @@
+package gogetter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JustinTulloss/gogetter/wildcard"
+)
+
+func TestDublinCore(t *testing.T) {
+	t.Parallel()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	doc := `<html>
+		<head>
+			<title>Report 12 | Ministry of Examples</title>
+			<meta name="DC.title" content="Annual report on examples" />
+			<meta name="DC.creator" content="Jane Doe" />
+			<meta name="DCTERMS.issued" content="2019-06-01" />
+			<meta name="DC.description" content="What happened to examples this year." />
+			<meta name="DC.publisher" content="Ministry of Examples" />
+			<meta name="DC.subject" content="examples; reports" />
+			<meta name="DC.subject" content="government" />
+		</head>
+	</html>`
+	expected := &wildcard.LinkCard{
+		Card: wildcard.Card{
+			CardType: wildcard.LinkType,
+		},
+		Target: &wildcard.LinkTarget{
+			Description: "What happened to examples this year.",
+			GenericMetadata: wildcard.GenericMetadata{
+				Title:           "Annual report on examples",
+				PublicationDate: timePtr(time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)),
+				Source:          "Ministry of Examples",
+				Keywords:        []string{"examples", "reports", "government"},
+			},
+		},
+	}
+	result, err := scraper.ParseTags(strings.NewReader(doc), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("%#v != %#v", result, expected)
+	}
+}
+
+func TestRDFaLite(t *testing.T) {
+	t.Parallel()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	doc := `<html prefix="dc: http://purl.org/dc/terms/">
+		<head><title>Blog</title></head>
+		<body vocab="http://schema.org/">
+			<div typeof="BlogPosting">
+				<h1 property="headline">Notes on RDFa</h1>
+				<p property="description">A short post about attributes.</p>
+				<span property="author" typeof="Person"><span property="name">Sam</span></span>
+				<time property="datePublished" datetime="2020-02-03T04:05:06Z">Feb 3</time>
+			</div>
+			<footer><span property="dc:publisher">Sam's Blog</span></footer>
+		</body>
+	</html>`
+	expected := &wildcard.ArticleCard{
+		Card: wildcard.Card{
+			CardType: wildcard.ArticleType,
+		},
+		Article: &wildcard.Article{
+			AbstractContent: "A short post about attributes.",
+			Byline:          "Sam",
+			GenericMetadata: wildcard.GenericMetadata{
+				Title:           "Notes on RDFa",
+				PublicationDate: timePtr(time.Date(2020, 2, 3, 4, 5, 6, 0, time.UTC)),
+				Source:          "Sam's Blog",
+			},
+		},
+	}
+	result, err := scraper.ParseTags(strings.NewReader(doc), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("%#v != %#v", result, expected)
+	}
+}
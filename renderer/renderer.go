@@ -0,0 +1,110 @@
+// Turns cards into HTML previews, for tools that would rather show a card
+// than deal with its JSON.
+package renderer
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"net/url"
+
+	"github.com/JustinTulloss/gogetter/wildcard"
+)
+
+// Renders cards with a template per card type. Any of them can be replaced,
+// and replacements can use the same "image", "title", "source" and "date"
+// templates the built in ones do.
+type Renderer struct {
+	templates map[wildcard.CardType]*template.Template
+	page      *template.Template
+}
+
+// What card templates are run with.
+type CardData struct {
+	Card wildcard.Wildcard
+	// The card's metadata, empty rather than nil for cards without any.
+	Metadata *wildcard.GenericMetadata
+	// Where the card's title links to.
+	Url string
+	// The host Url is on, for cards that don't say where they're from.
+	Host string
+	// Whatever describes the card best.
+	Description string
+}
+
+// Creates a renderer with the built in templates.
+func New() (*Renderer, error) {
+	r := &Renderer{templates: make(map[wildcard.CardType]*template.Template)}
+	for cardType, text := range cardTemplates {
+		if err := r.SetTemplate(wildcard.CardType(cardType), text); err != nil {
+			return nil, err
+		}
+	}
+	page, err := template.New("page").Parse(pageTemplate)
+	if err != nil {
+		return nil, err
+	}
+	r.page = page
+	return r, nil
+}
+
+// Replaces the template for cardType. text is an html/template, run with
+// CardData.
+func (r *Renderer) SetTemplate(cardType wildcard.CardType, text string) error {
+	tmpl, err := template.New(string(cardType)).Parse(partials)
+	if err != nil {
+		return err
+	}
+	if _, err := tmpl.Parse(text); err != nil {
+		return fmt.Errorf("Could not parse the %s template: %s", cardType, err)
+	}
+	r.templates[cardType] = tmpl
+	return nil
+}
+
+// Writes card out as a snippet of HTML.
+func (r *Renderer) Render(w io.Writer, card wildcard.Wildcard) error {
+	tmpl, ok := r.templates[card.BaseCard().CardType]
+	if !ok {
+		tmpl, ok = r.templates[wildcard.LinkType]
+	}
+	if !ok {
+		return fmt.Errorf("No template for %s cards", card.BaseCard().CardType)
+	}
+	return tmpl.Execute(w, newCardData(card))
+}
+
+// Writes card out as a page of its own, which is meant to be shown in an
+// iframe. The page doesn't need any scripts, so it's best served with a
+// Content-Security-Policy that doesn't allow them.
+func (r *Renderer) RenderPage(w io.Writer, card wildcard.Wildcard) error {
+	var snippet bytes.Buffer
+	if err := r.Render(&snippet, card); err != nil {
+		return err
+	}
+	data := newCardData(card)
+	return r.page.Execute(w, struct {
+		Card     template.HTML
+		Metadata *wildcard.GenericMetadata
+	}{template.HTML(snippet.String()), data.Metadata})
+}
+
+func newCardData(card wildcard.Wildcard) *CardData {
+	data := &CardData{
+		Card:        card,
+		Metadata:    card.Metadata(),
+		Url:         wildcard.TopicUrl(card),
+		Description: wildcard.TopicDescription(card),
+	}
+	if data.Metadata == nil {
+		data.Metadata = &wildcard.GenericMetadata{}
+	}
+	if data.Url == "" {
+		data.Url = card.BaseCard().WebUrl
+	}
+	if parsed, err := url.Parse(data.Url); err == nil {
+		data.Host = parsed.Host
+	}
+	return data
+}
@@ -0,0 +1,72 @@
+package renderer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/JustinTulloss/gogetter/wildcard"
+)
+
+func TestRenderEscapes(t *testing.T) {
+	t.Parallel()
+	r, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	card := wildcard.NewArticleCard("http://example.com/a", "javascript:alert(1)")
+	card.Article.Title = `<script>alert("title")</script>`
+	card.Article.AbstractContent = `"><img src=x onerror=alert(1)>`
+	card.Article.Image = &wildcard.ImageDetails{ImageUrl: `http://example.com/a.jpg" onload="alert(1)`}
+	var out bytes.Buffer
+	if err := r.RenderPage(&out, card); err != nil {
+		t.Fatal(err)
+	}
+	html := out.String()
+	for _, unsafe := range []string{"<script>", "<img src=x", "javascript:", `" onload="`} {
+		if strings.Contains(html, unsafe) {
+			t.Errorf("Rendered card contains %q:\n%s", unsafe, html)
+		}
+	}
+	if !strings.Contains(html, "&lt;script&gt;alert(&#34;title&#34;)&lt;/script&gt;") {
+		t.Errorf("Title wasn't escaped:\n%s", html)
+	}
+}
+
+func TestRenderTemplates(t *testing.T) {
+	t.Parallel()
+	r, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, card := range []wildcard.Wildcard{
+		wildcard.NewArticleCard("http://example.com/", "http://example.com/"),
+		wildcard.NewVideoCard("http://example.com/"),
+		wildcard.NewImageCard("http://example.com/", "http://example.com/a.png"),
+		wildcard.NewLinkCard("http://example.com/", "http://example.com/"),
+		wildcard.NewDocumentCard("http://example.com/", "http://example.com/a.pdf"),
+		wildcard.NewFeedCard("http://example.com/", "http://example.com/feed"),
+		wildcard.NewPlaceCard("http://example.com/"),
+		wildcard.NewProductCard("http://example.com/", "http://example.com/"),
+		&wildcard.ArticleCard{Card: wildcard.Card{CardType: wildcard.ArticleType}},
+	} {
+		var out bytes.Buffer
+		if err := r.Render(&out, card); err != nil {
+			t.Errorf("Could not render %s card: %s", card.BaseCard().CardType, err)
+		}
+	}
+
+	if err := r.SetTemplate(wildcard.LinkType, `<b>{{template "title" .}}</b>`); err != nil {
+		t.Fatal(err)
+	}
+	card := wildcard.NewLinkCard("http://example.com/", "http://example.com/")
+	card.Target.Title = "Example"
+	var out bytes.Buffer
+	if err := r.Render(&out, card); err != nil {
+		t.Fatal(err)
+	}
+	expected := `<b><a class="gogetter-title" href="http://example.com/" target="_blank" rel="noopener noreferrer nofollow">Example</a></b>`
+	if out.String() != expected {
+		t.Errorf("%s != %s", out.String(), expected)
+	}
+}
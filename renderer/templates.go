@@ -0,0 +1,106 @@
+package renderer
+
+// The pieces every card template can use. Everything that came from the
+// page goes through html/template, so it's escaped for wherever it ends up,
+// and urls with schemes like javascript: are thrown out.
+const partials = `
+{{define "image"}}{{with .Metadata.Image}}<img class="gogetter-image" src="{{.ImageUrl}}" alt=""{{if .Width}} width="{{.Width}}"{{end}}{{if .Height}} height="{{.Height}}"{{end}} loading="lazy" referrerpolicy="no-referrer">{{end}}{{end}}
+{{define "title"}}{{with .Metadata.Title}}<a class="gogetter-title" href="{{$.Url}}" target="_blank" rel="noopener noreferrer nofollow">{{.}}</a>{{end}}{{end}}
+{{define "source"}}<div class="gogetter-source">{{with .Metadata.SourceIcon}}<img class="gogetter-icon" src="{{.}}" alt="" width="16" height="16" referrerpolicy="no-referrer">{{end}}{{if .Metadata.Source}}{{.Metadata.Source}}{{else}}{{.Host}}{{end}}</div>{{end}}
+{{define "date"}}{{with .Metadata.PublicationDate}}<time class="gogetter-date" datetime="{{.Format "2006-01-02T15:04:05Z07:00"}}">{{.Format "Jan 2, 2006"}}</time>{{end}}{{end}}
+`
+
+// The templates for each kind of card, by card type. Cards without one of
+// their own use the link template.
+var cardTemplates = map[string]string{
+	"article": `<div class="gogetter-card gogetter-article">
+{{template "image" .}}
+{{template "title" .}}
+{{with .Card.Article}}{{with .Byline}}<div class="gogetter-byline">{{.}}</div>{{end}}
+{{with .AbstractContent}}<p class="gogetter-description">{{.}}</p>{{end}}{{end}}
+{{template "date" .}}
+{{template "source" .}}
+</div>`,
+
+	"video": `<div class="gogetter-card gogetter-video">
+{{with .Card.Media}}{{if .EmbeddedUrl}}<iframe class="gogetter-player" src="{{.EmbeddedUrl}}"{{with .EmbeddedUrlWidth}} width="{{.}}"{{end}}{{with .EmbeddedUrlHeight}} height="{{.}}"{{end}} sandbox="allow-scripts allow-same-origin allow-popups allow-presentation" allowfullscreen referrerpolicy="no-referrer"></iframe>{{else}}{{template "image" $}}{{end}}{{end}}
+{{template "title" .}}
+{{with .Card.Media}}{{with .Description}}<p class="gogetter-description">{{.}}</p>{{end}}{{end}}
+{{template "source" .}}
+</div>`,
+
+	"image": `<div class="gogetter-card gogetter-image-card">
+{{with .Card.Media}}<a href="{{$.Url}}" target="_blank" rel="noopener noreferrer nofollow"><img class="gogetter-image" src="{{.ImageUrl}}" alt="{{.ImageCaption}}"{{if .Width}} width="{{.Width}}"{{end}}{{if .Height}} height="{{.Height}}"{{end}} referrerpolicy="no-referrer"></a>
+{{with .ImageCaption}}<p class="gogetter-description">{{.}}</p>{{end}}{{end}}
+{{template "title" .}}
+{{template "source" .}}
+</div>`,
+
+	"link": `<div class="gogetter-card gogetter-link">
+{{template "image" .}}
+{{if .Metadata.Title}}{{template "title" .}}{{else}}<a class="gogetter-title" href="{{.Url}}" target="_blank" rel="noopener noreferrer nofollow">{{.Url}}</a>{{end}}
+{{with .Description}}<p class="gogetter-description">{{.}}</p>{{end}}
+{{template "source" .}}
+</div>`,
+
+	"document": `<div class="gogetter-card gogetter-document">
+{{template "title" .}}
+{{with .Card.Document}}{{with .Author}}<div class="gogetter-byline">{{.}}</div>{{end}}
+{{with .Subject}}<p class="gogetter-description">{{.}}</p>{{end}}
+{{with .PageCount}}<div class="gogetter-pages">{{.}} pages</div>{{end}}{{end}}
+{{template "source" .}}
+</div>`,
+
+	"feed": `<div class="gogetter-card gogetter-feed">
+{{template "image" .}}
+{{template "title" .}}
+{{with .Card.Feed}}{{with .Description}}<p class="gogetter-description">{{.}}</p>{{end}}
+{{with .Items}}<ul class="gogetter-items">{{range .}}<li>{{if .Url}}<a href="{{.Url}}" target="_blank" rel="noopener noreferrer nofollow">{{or .Title .Url}}</a>{{else}}{{.Title}}{{end}}</li>{{end}}</ul>{{end}}{{end}}
+{{template "source" .}}
+</div>`,
+
+	"place": `<div class="gogetter-card gogetter-place">
+{{template "image" .}}
+{{template "title" .}}
+{{with .Card.Place}}{{with .Address}}<address class="gogetter-address">{{.Formatted}}</address>{{end}}
+{{with .PhoneNumber}}<div class="gogetter-phone">{{.}}</div>{{end}}
+{{with .Rating}}<div class="gogetter-rating">{{.Value}}{{with .BestRating}} / {{.}}{{end}}</div>{{end}}
+{{with .Description}}<p class="gogetter-description">{{.}}</p>{{end}}{{end}}
+{{template "source" .}}
+</div>`,
+
+	"product": `<div class="gogetter-card gogetter-product">
+{{template "image" .}}
+{{template "title" .}}
+{{with .Card.Product}}{{with .Brand}}<div class="gogetter-brand">{{.}}</div>{{end}}
+{{with .Price}}<div class="gogetter-price">{{.}}{{with $.Card.Product.Currency}} {{.}}{{end}}</div>{{end}}
+{{with .Availability}}<div class="gogetter-availability">{{.}}</div>{{end}}
+{{with .Rating}}<div class="gogetter-rating">{{.Value}}{{with .BestRating}} / {{.}}{{end}}</div>{{end}}
+{{with .Description}}<p class="gogetter-description">{{.}}</p>{{end}}{{end}}
+{{template "source" .}}
+</div>`,
+}
+
+// Wraps a card in a page of its own, for showing in an iframe.
+const pageTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="referrer" content="no-referrer">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Metadata.Title}}</title>
+<style>
+body { margin: 0; font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; font-size: 14px; color: #1d2129; }
+.gogetter-card { border: 1px solid #dddfe2; border-radius: 4px; overflow: hidden; max-width: 520px; }
+.gogetter-card > * { margin: 4px 12px; }
+.gogetter-card > .gogetter-image, .gogetter-card > .gogetter-player, .gogetter-card > a > .gogetter-image { display: block; margin: 0; width: 100%; height: auto; }
+.gogetter-title { display: block; font-weight: bold; font-size: 16px; color: inherit; text-decoration: none; margin-top: 10px; }
+.gogetter-description { color: #606770; }
+.gogetter-source { color: #606770; font-size: 12px; text-transform: uppercase; margin-bottom: 10px; }
+.gogetter-icon { vertical-align: middle; margin-right: 4px; }
+</style>
+</head>
+<body>
+{{.Card}}
+</body>
+</html>`
@@ -0,0 +1,220 @@
+package gogetter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+)
+
+// A site specific rule for pages whose open graph tags are broken or missing.
+// Each field is a CSS selector for where to find that piece of metadata.
+// The text of the first match is used, unless the selector ends with
+// @attribute, in which case it's that attribute of the first match that has
+// it, e.g. "img.hero@src".
+type Rule struct {
+	// Hosts the rule applies to. Patterns can use * and friends, so
+	// "*.example.com" covers every subdomain of example.com.
+	Hosts       []string `json:"hosts"`
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Image       string   `json:"image,omitempty"`
+	Author      string   `json:"author,omitempty"`
+	Date        string   `json:"date,omitempty"`
+}
+
+// What a rules file looks like:
+//
+//	{"rules": [{"hosts": ["wiki.example.com"], "title": "h1#title", "image": "meta[name=thumbnail]@content"}]}
+type RuleSet struct {
+	Rules []Rule `json:"rules"`
+}
+
+// The tag each rule field fills in.
+var ruleTags = []struct {
+	tag   string
+	field func(*Rule) string
+}{
+	{"og:title", func(r *Rule) string { return r.Title }},
+	{"og:description", func(r *Rule) string { return r.Description }},
+	{"og:image", func(r *Rule) string { return r.Image }},
+	{"byl", func(r *Rule) string { return r.Author }},
+	{"article:published_time", func(r *Rule) string { return r.Date }},
+}
+
+type ruleSelector struct {
+	selector  cascadia.Selector
+	attribute string
+}
+
+type compiledRule struct {
+	hosts     []string
+	selectors map[string]ruleSelector
+}
+
+// An extractor that applies the rules in a rules file. What the rules find
+// beats what the page says about itself, so it belongs ahead of the other
+// extractors. The file can be reloaded while the extractor is in use.
+type RulesExtractor struct {
+	path  string
+	mutex sync.RWMutex
+	rules []compiledRule
+}
+
+// Loads the rules in the file at path, which is JSON shaped like a RuleSet.
+func NewRulesExtractor(path string) (*RulesExtractor, error) {
+	extractor := &RulesExtractor{path: path}
+	if err := extractor.Reload(); err != nil {
+		return nil, err
+	}
+	return extractor, nil
+}
+
+// Reads the rules file again. If it's broken, the rules we already had are
+// kept.
+func (e *RulesExtractor) Reload() error {
+	file, err := os.Open(e.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var ruleSet RuleSet
+	if err := json.NewDecoder(file).Decode(&ruleSet); err != nil {
+		return fmt.Errorf("Could not read rules from %s: %s", e.path, err)
+	}
+	rules, err := compileRules(ruleSet.Rules)
+	if err != nil {
+		return fmt.Errorf("Could not read rules from %s: %s", e.path, err)
+	}
+	e.mutex.Lock()
+	e.rules = rules
+	e.mutex.Unlock()
+	return nil
+}
+
+func compileRules(rules []Rule) ([]compiledRule, error) {
+	compiled := make([]compiledRule, 0, len(rules))
+	for i := range rules {
+		rule := &rules[i]
+		if len(rule.Hosts) == 0 {
+			return nil, fmt.Errorf("rule %d has no hosts", i)
+		}
+		current := compiledRule{selectors: make(map[string]ruleSelector)}
+		for _, host := range rule.Hosts {
+			host = strings.ToLower(host)
+			if _, err := path.Match(host, ""); err != nil {
+				return nil, fmt.Errorf("rule %d has a bad host pattern %q", i, host)
+			}
+			current.hosts = append(current.hosts, host)
+		}
+		for _, ruleTag := range ruleTags {
+			selector := ruleTag.field(rule)
+			if selector == "" {
+				continue
+			}
+			var attribute string
+			if at := strings.LastIndex(selector, "@"); at >= 0 && !strings.ContainsAny(selector[at:], "] ") {
+				selector, attribute = selector[:at], selector[at+1:]
+			}
+			parsed, err := cascadia.Compile(selector)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d has a bad selector %q: %s", i, selector, err)
+			}
+			current.selectors[ruleTag.tag] = ruleSelector{parsed, attribute}
+		}
+		compiled = append(compiled, current)
+	}
+	return compiled, nil
+}
+
+// Applies the first rule that matches the page's host.
+func (e *RulesExtractor) Extract(page *Page) error {
+	parsed, err := url.Parse(page.Url)
+	if err != nil || parsed.Hostname() == "" {
+		return nil
+	}
+	host := strings.ToLower(parsed.Hostname())
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	for _, rule := range e.rules {
+		if !rule.matches(host) {
+			continue
+		}
+		for tag, selector := range rule.selectors {
+			if _, alreadySet := page.Tag(tag); alreadySet {
+				continue
+			}
+			value := selector.find(page.Doc)
+			if value == "" {
+				continue
+			}
+			if tag == "og:image" {
+				resolved, ok := resolveUrl(page.Url, value)
+				if !ok {
+					continue
+				}
+				value = resolved
+			}
+			page.AddTag(tag, value)
+		}
+		return nil
+	}
+	return nil
+}
+
+func (r *compiledRule) matches(host string) bool {
+	for _, pattern := range r.hosts {
+		if matched, _ := path.Match(pattern, host); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *ruleSelector) find(doc *goquery.Document) string {
+	matches := doc.FindMatcher(s.selector)
+	if s.attribute == "" {
+		return normalizeWhitespace(matches.First().Text())
+	}
+	var value string
+	matches.EachWithBreak(func(i int, selection *goquery.Selection) bool {
+		attribute, ok := selection.Attr(s.attribute)
+		value = strings.TrimSpace(attribute)
+		return !ok || value == ""
+	})
+	return value
+}
+
+// Loads site specific rules from the file at path and runs them ahead of the
+// other extractors. Calling it again replaces the rules.
+func (s *Scraper) LoadRules(path string) error {
+	rules, err := NewRulesExtractor(path)
+	if err != nil {
+		return err
+	}
+	for i, extractor := range s.extractors {
+		if _, ok := extractor.(*RulesExtractor); ok {
+			s.extractors[i] = rules
+			s.rules = rules
+			return nil
+		}
+	}
+	s.extractors = append([]Extractor{rules}, s.extractors...)
+	s.rules = rules
+	return nil
+}
+
+// Reads the rules file given to LoadRules again, keeping the rules we
+// already had if it's broken.
+func (s *Scraper) ReloadRules() error {
+	if s.rules == nil {
+		return nil
+	}
+	return s.rules.Reload()
+}
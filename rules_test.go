@@ -0,0 +1,132 @@
+package gogetter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JustinTulloss/gogetter/wildcard"
+)
+
+func TestRules(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gogetter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rulesFile := filepath.Join(dir, "rules.json")
+	rules := `{"rules": [
+		{"hosts": ["*.example.com"], "title": "h1.title", "image": "img.hero@data-src", "author": ".byline a"}
+	]}`
+	if err := ioutil.WriteFile(rulesFile, []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	if err := scraper.LoadRules(rulesFile); err != nil {
+		t.Fatal(err)
+	}
+	doc := `<html>
+		<head>
+			<meta property="og:title" content="Wiki" />
+		</head>
+		<body>
+			<h1 class="title">  The real
+				title </h1>
+			<img class="hero" src="/spinner.gif" data-src="/hero.jpg" />
+		</body>
+	</html>`
+	expected := &wildcard.LinkCard{
+		Card: wildcard.Card{
+			CardType: wildcard.LinkType,
+			WebUrl:   "http://wiki.example.com/page",
+		},
+		Target: &wildcard.LinkTarget{
+			Url: "http://wiki.example.com/page",
+			GenericMetadata: wildcard.GenericMetadata{
+				Title: "The real title",
+				Image: &wildcard.ImageDetails{
+					ImageUrl: "http://wiki.example.com/hero.jpg",
+				},
+			},
+		},
+	}
+	result, err := scraper.ParseTags(strings.NewReader(doc), "http://wiki.example.com/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("%#v != %#v", result, expected)
+	}
+
+	// A broken file leaves the old rules in place.
+	if err := ioutil.WriteFile(rulesFile, []byte(`{"rules": [{"hosts": ["*"], "title": "h1["}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := scraper.ReloadRules(); err == nil {
+		t.Errorf("Expected a bad selector to fail")
+	}
+	result, err = scraper.ParseTags(strings.NewReader(doc), "http://wiki.example.com/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("%#v != %#v", result, expected)
+	}
+}
+
+func TestRulesFillArticles(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gogetter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rulesFile := filepath.Join(dir, "rules.json")
+	rules := `{"rules": [
+		{"hosts": ["news.example.com"], "author": ".byline a", "date": ".dateline@data-published"}
+	]}`
+	if err := ioutil.WriteFile(rulesFile, []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	if err := scraper.LoadRules(rulesFile); err != nil {
+		t.Fatal(err)
+	}
+	doc := `<html>
+		<head>
+			<meta property="og:type" content="article" />
+			<meta property="og:title" content="A story" />
+			<meta property="og:description" content="About something" />
+		</head>
+		<body>
+			<p class="byline">By <a href="/staff/jane">Jane Doe</a></p>
+			<span class="dateline" data-published="2016-05-06T07:08:09Z">May 6</span>
+		</body>
+	</html>`
+	result, err := scraper.ParseTags(strings.NewReader(doc), "http://news.example.com/story")
+	if err != nil {
+		t.Fatal(err)
+	}
+	article, ok := result.(*wildcard.ArticleCard)
+	if !ok {
+		t.Fatalf("Expected an article card, got %#v", result)
+	}
+	if article.Article.Byline != "Jane Doe" {
+		t.Errorf("Byline was %q", article.Article.Byline)
+	}
+	published := time.Date(2016, 5, 6, 7, 8, 9, 0, time.UTC)
+	if date := article.Article.PublicationDate; date == nil || !date.Equal(published) {
+		t.Errorf("Publication date was %v", date)
+	}
+}
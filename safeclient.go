@@ -0,0 +1,128 @@
+package gogetter
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+var errPrivateAddress = errors.New("Refusing to connect to a private address")
+
+// Networks that aren't on the internet, or that are special in some way we
+// don't want strangers poking at, like the cloud metadata service.
+var privateNetworks []*net.IPNet
+
+func init() {
+	for _, cidr := range []string{
+		"0.0.0.0/8",
+		"10.0.0.0/8",
+		"100.64.0.0/10",
+		"127.0.0.0/8",
+		"169.254.0.0/16",
+		"172.16.0.0/12",
+		"192.0.0.0/24",
+		"192.168.0.0/16",
+		"198.18.0.0/15",
+		"224.0.0.0/4",
+		"240.0.0.0/4",
+		"::/128",
+		"::1/128",
+		"64:ff9b::/96",
+		"fc00::/7",
+		"fe80::/10",
+		"ff00::/8",
+	} {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		privateNetworks = append(privateNetworks, network)
+	}
+}
+
+func isPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Checks every address we're about to connect to, after DNS, so a public
+// name that resolves to a private address doesn't get through either.
+func refusePrivateAddresses(network, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s", errPrivateAddress, host)
+	}
+	return nil
+}
+
+// The transport behind every client that fetches urls strangers gave us. It
+// won't connect to anything on our own network, including through redirects.
+func newSafeTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: refusePrivateAddresses,
+	}
+	return &http.Transport{
+		// Going through a proxy would mean only the proxy's address
+		// gets checked.
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		MaxIdleConnsPerHost:   4,
+	}
+}
+
+// Creates a client for fetching urls that came from someone we don't trust.
+// It won't connect to anything on our own network, including through
+// redirects, and gives up after timeout.
+func NewSafeClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: newSafeTransport(),
+		Timeout:   timeout,
+	}
+}
+
+// Tries requests again when they fail before there's a response, since the
+// sites we scrape aren't always quick to answer. Only requests that are safe
+// to repeat are retried, and never ones we refused to make.
+type retryTransport struct {
+	transport http.RoundTripper
+	maxTries  int
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+	var err error
+	for try := 0; try < t.maxTries; try++ {
+		resp, err = t.transport.RoundTrip(req)
+		if err == nil || !shouldRetry(req, err) {
+			break
+		}
+	}
+	return resp, err
+}
+
+func shouldRetry(req *http.Request, err error) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		return false
+	}
+	return req.Context().Err() == nil && !errors.Is(err, errPrivateAddress)
+}
@@ -0,0 +1,134 @@
+package gogetter
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	t.Parallel()
+	for address, expected := range map[string]bool{
+		"8.8.8.8":            true,
+		"2606:4700::1111":    true,
+		"127.0.0.1":          false,
+		"10.1.2.3":           false,
+		"172.20.0.1":         false,
+		"192.168.1.1":        false,
+		"169.254.169.254":    false,
+		"::1":                false,
+		"fd00::1":            false,
+		"::ffff:127.0.0.1":   false,
+		"0.0.0.0":            false,
+		"100.64.0.1":         false,
+		"fe80::1":            false,
+		"::ffff:192.168.0.1": false,
+	} {
+		if isPublicIP(net.ParseIP(address)) != expected {
+			t.Errorf("%s should be public: %v", address, expected)
+		}
+	}
+}
+
+func TestSafeClientRefusesPrivateAddresses(t *testing.T) {
+	t.Parallel()
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`<html><head><title>Internal</title></head></html>`))
+	}))
+	defer server.Close()
+
+	_, err := NewSafeClient(5 * time.Second).Get(server.URL)
+	if err == nil || !strings.Contains(err.Error(), errPrivateAddress.Error()) {
+		t.Errorf("Expected the safe client to refuse %s, got %v", server.URL, err)
+	}
+
+	scraper, err := NewScraper("", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scraper.ScrapeTags(server.URL); err == nil || !strings.Contains(err.Error(), errPrivateAddress.Error()) {
+		t.Errorf("Expected the scraper to refuse %s, got %v", server.URL, err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no requests to get through, %d did", requests)
+	}
+}
+
+// Fails the first failures requests without answering, then hands the rest
+// to the real transport.
+type flakyTransport struct {
+	failures int
+	requests int
+}
+
+func (t *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests++
+	if t.requests <= t.failures {
+		return nil, errors.New("connection reset by peer")
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestRetryTransport(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	flaky := &flakyTransport{failures: 2}
+	client := &http.Client{Transport: &retryTransport{transport: flaky, maxTries: 3}}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected the third try to work, got %s", err)
+	}
+	resp.Body.Close()
+	if flaky.requests != 3 {
+		t.Errorf("Made %d requests instead of 3", flaky.requests)
+	}
+
+	flaky = &flakyTransport{failures: 3}
+	client = &http.Client{Transport: &retryTransport{transport: flaky, maxTries: 3}}
+	if _, err := client.Get(server.URL); err == nil {
+		t.Errorf("Expected to give up after 3 tries")
+	}
+	if flaky.requests != 3 {
+		t.Errorf("Made %d requests instead of 3", flaky.requests)
+	}
+
+	// Posts aren't safe to repeat.
+	flaky = &flakyTransport{failures: 1}
+	client = &http.Client{Transport: &retryTransport{transport: flaky, maxTries: 3}}
+	if _, err := client.Post(server.URL, "text/plain", strings.NewReader("hi")); err == nil {
+		t.Errorf("Expected a failed post not to be retried")
+	}
+	if flaky.requests != 1 {
+		t.Errorf("Made %d requests instead of 1", flaky.requests)
+	}
+
+	// Addresses we refuse to connect to are refused once.
+	safe := &countingTransport{transport: newSafeTransport()}
+	client = &http.Client{Transport: &retryTransport{transport: safe, maxTries: 3}}
+	if _, err := client.Get(server.URL); err == nil {
+		t.Errorf("Expected the private address to be refused")
+	}
+	if safe.requests != 1 {
+		t.Errorf("Tried a private address %d times", safe.requests)
+	}
+}
+
+type countingTransport struct {
+	transport http.RoundTripper
+	requests  int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests++
+	return t.transport.RoundTrip(req)
+}
@@ -0,0 +1,198 @@
+package gogetter
+
+import (
+	"sort"
+	"strings"
+)
+
+// An item of schema.org structured data, which can come from JSON-LD or
+// microdata. Property values are either strings or nested items.
+type schemaItem struct {
+	Types      []string
+	Properties map[string][]interface{}
+}
+
+func (i *schemaItem) add(name string, value interface{}) {
+	if i.Properties == nil {
+		i.Properties = make(map[string][]interface{})
+	}
+	i.Properties[name] = append(i.Properties[name], value)
+}
+
+// schema.org types we can make a card out of, and the og:type they're the
+// same as.
+var schemaCardTypes = map[string]string{
+	"Article":           "article",
+	"BlogPosting":       "article",
+	"NewsArticle":       "article",
+	"Report":            "article",
+	"ScholarlyArticle":  "article",
+	"TechArticle":       "article",
+	"Product":           "product",
+	"Place":             "place",
+	"LocalBusiness":     "place",
+	"Restaurant":        "place",
+	"CafeOrCoffeeShop":  "place",
+	"BarOrPub":          "place",
+	"FoodEstablishment": "place",
+	"Hotel":             "place",
+	"LodgingBusiness":   "place",
+	"Store":             "place",
+	"TouristAttraction": "place",
+	"Museum":            "place",
+	"VideoObject":       "video.other",
+}
+
+// The properties we turn into tags, with nested properties as paths. They
+// end up as "schema:<path>", which the aliases map onto the card.
+var schemaProperties = []string{
+	"headline",
+	"name",
+	"description",
+	"image",
+	"datePublished",
+	"author",
+	"publisher",
+	"brand",
+	"sku",
+	"offers:price",
+	"offers:lowPrice",
+	"offers:priceCurrency",
+	"offers:availability",
+	"aggregateRating:ratingValue",
+	"aggregateRating:bestRating",
+	"aggregateRating:worstRating",
+	"aggregateRating:ratingCount",
+	"aggregateRating:reviewCount",
+	"address:streetAddress",
+	"address:postOfficeBoxNumber",
+	"address:addressLocality",
+	"address:addressRegion",
+	"address:postalCode",
+	"address:addressCountry",
+	"geo:latitude",
+	"geo:longitude",
+	"geo:elevation",
+	"telephone",
+}
+
+// When a property we want as a string is an item instead, like an author
+// that's a Person, the item stands in with one of these properties. Most
+// things go by their name, images by where they are.
+var schemaStandIns = map[string][]string{
+	"image": {"url", "contentUrl"},
+}
+
+var defaultSchemaStandIns = []string{"name"}
+
+// Turns a schema.org type or property into its short name, so
+// "http://schema.org/Product" and "schema:Product" are both "Product".
+func schemaName(name string) string {
+	name = strings.TrimSpace(name)
+	for _, prefix := range []string{"http://schema.org/", "https://schema.org/", "schema:"} {
+		if strings.HasPrefix(name, prefix) {
+			return strings.TrimPrefix(name, prefix)
+		}
+	}
+	return name
+}
+
+func (i *schemaItem) cardType() string {
+	for _, itemType := range i.Types {
+		if cardType, ok := schemaCardTypes[itemType]; ok {
+			return cardType
+		}
+	}
+	return ""
+}
+
+// The item the page is about, which is the first one we can make a card out
+// of. Top level items are looked at before the ones nested in them, since a
+// page's item can mention plenty of others.
+func mainSchemaItem(items []*schemaItem) *schemaItem {
+	queue := append([]*schemaItem(nil), items...)
+	seen := make(map[*schemaItem]bool)
+	for len(queue) > 0 {
+		item := queue[0]
+		queue = queue[1:]
+		if seen[item] {
+			continue
+		}
+		seen[item] = true
+		if item.cardType() != "" {
+			return item
+		}
+		// Go randomizes map order, so sort to keep results stable.
+		names := make([]string, 0, len(item.Properties))
+		for name := range item.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, value := range item.Properties[name] {
+				if nested, ok := value.(*schemaItem); ok {
+					queue = append(queue, nested)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Adds what we understand about item to the page's tags, as
+// schema:<property>. Properties with more than one value add them all, the
+// first one is what the card gets.
+func addSchemaTags(item *schemaItem, page *Page) {
+	if cardType := item.cardType(); cardType != "" {
+		page.AddTag("schema:type", cardType)
+	}
+	for _, property := range schemaProperties {
+		for _, value := range item.texts(strings.Split(property, ":")) {
+			if property == "offers:availability" {
+				// These are schema.org urls like http://schema.org/InStock
+				value = schemaName(value)
+			}
+			page.AddTag("schema:"+property, value)
+		}
+	}
+}
+
+// Follows path through nested items to the first string at the end of it.
+func (i *schemaItem) text(path []string) (string, bool) {
+	texts := i.texts(path)
+	if len(texts) == 0 {
+		return "", false
+	}
+	return texts[0], true
+}
+
+// Follows path through nested items to every string at the end of it, in
+// order. Items in the way stand in for a string with their name or the like.
+func (i *schemaItem) texts(path []string) []string {
+	var texts []string
+	for _, value := range i.Properties[path[0]] {
+		switch value := value.(type) {
+		case string:
+			value = strings.TrimSpace(value)
+			if len(path) == 1 && value != "" {
+				texts = append(texts, value)
+			}
+		case *schemaItem:
+			if len(path) > 1 {
+				texts = append(texts, value.texts(path[1:])...)
+				continue
+			}
+			standIns, ok := schemaStandIns[path[0]]
+			if !ok {
+				standIns = defaultSchemaStandIns
+			}
+			for _, standIn := range standIns {
+				if text, ok := value.text([]string{standIn}); ok {
+					texts = append(texts, text)
+					break
+				}
+			}
+		}
+	}
+	return texts
+}
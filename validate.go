@@ -0,0 +1,332 @@
+package gogetter
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/temoto/robotstxt.go"
+)
+
+// How bad a problem with a page is.
+type Severity string
+
+const (
+	// The page won't preview properly until it's fixed.
+	SeverityError Severity = "error"
+	// The page previews, but not as well as it could.
+	SeverityWarning Severity = "warning"
+	// Worth knowing about, but nothing needs to change.
+	SeverityInfo Severity = "info"
+)
+
+var severityOrder = map[Severity]int{
+	SeverityError:   0,
+	SeverityWarning: 1,
+	SeverityInfo:    2,
+}
+
+// Whichever of a and b is less bad.
+func mildest(a, b Severity) Severity {
+	if severityOrder[a] > severityOrder[b] {
+		return a
+	}
+	return b
+}
+
+// A problem with a page and what to do about it.
+type Finding struct {
+	Severity Severity `json:"severity"`
+	// The tag the problem is with, if it's with a tag.
+	Tag        string `json:"tag,omitempty"`
+	Problem    string `json:"problem"`
+	Suggestion string `json:"suggestion"`
+}
+
+// What Validate found wrong with a page, worst problems first.
+type ValidationReport struct {
+	Url      string    `json:"url"`
+	Findings []Finding `json:"findings"`
+	// Everything we found on the page, see ParseRawTags.
+	Tags map[string][]string `json:"tags,omitempty"`
+}
+
+func (r *ValidationReport) add(severity Severity, tag, problem, suggestion string) {
+	r.Findings = append(r.Findings, Finding{
+		Severity:   severity,
+		Tag:        tag,
+		Problem:    problem,
+		Suggestion: suggestion,
+	})
+}
+
+// Crawlers whose previews people care about. Being blocked for any of them
+// means no preview there, even if we can fetch the page.
+var previewCrawlers = []string{"facebookexternalhit", "Twitterbot", "LinkedInBot", "Slackbot"}
+
+// The open graph tags every page should have, how bad it is when they're
+// missing and what happens instead. Tags whose aliases turn up are at most
+// a warning, since something still shows up.
+var requiredTags = []struct {
+	tag      string
+	severity Severity
+	missing  string
+}{
+	{"og:title", SeverityError, "The preview won't have a title."},
+	{"og:image", SeverityError, "The preview won't have an image."},
+	{"og:description", SeverityWarning, "The preview won't have a description."},
+	{"og:url", SeverityWarning, "The url the page was fetched from is used, so shares of the same page under different urls are counted separately."},
+	{"og:type", SeverityInfo, "The page is treated as a website."},
+}
+
+// Tags that only make sense once per page. Tags like og:image can be repeated
+// on purpose.
+var singleValuedTags = []string{
+	"og:title",
+	"og:description",
+	"og:url",
+	"og:type",
+	"og:site_name",
+	"og:locale",
+	"twitter:card",
+	"twitter:title",
+	"twitter:description",
+	"article:published_time",
+	"article:modified_time",
+	"description",
+}
+
+// Tags that should hold dates.
+var dateTags = []string{
+	"article:modified_time",
+	"article:expiration_time",
+	"og:updated_time",
+}
+
+// Facebook cuts titles off around here, and most other sites are similar.
+const maxTitleLength = 88
+
+// Images smaller than this aren't shown at all, and ones smaller than the
+// recommended size are shown as a thumbnail instead of a big image.
+const (
+	minImageWidth          = 200
+	minImageHeight         = 200
+	recommendedImageWidth  = 1200
+	recommendedImageHeight = 630
+)
+
+// Fetches url the way a sharing site would and reports everything that
+// would make its preview look worse than it should, in the spirit of
+// Facebook's Sharing Debugger. Problems with the page are findings rather
+// than errors, so the only errors are for urls that can't be validated at
+// all.
+func (s *Scraper) Validate(ctx context.Context, pageUrl string) (*ValidationReport, error) {
+	if parsed, err := url.Parse(pageUrl); err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("%q is not a url we can validate", pageUrl)
+	}
+	report := &ValidationReport{Url: pageUrl, Findings: []Finding{}}
+	robots, path := s.validateRobotsTxt(ctx, report)
+	page := s.validateFetch(ctx, report, robots, path)
+	if page != nil {
+		report.Tags = page.RawTags()
+		validateTags(page, report.Tags, s.aliases, report)
+		s.validateImage(ctx, report)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		return severityOrder[report.Findings[i].Severity] < severityOrder[report.Findings[j].Severity]
+	})
+	return report, nil
+}
+
+// Checks the preview crawlers are let in. Returns the robots.txt, if there
+// is one, and the path it applies to so the page fetch doesn't need to get
+// it again.
+func (s *Scraper) validateRobotsTxt(ctx context.Context, report *ValidationReport) (*robotstxt.RobotsData, string) {
+	robots, path, err := s.fetchRobotsTxt(ctx, report.Url)
+	if err != nil || robots == nil {
+		return nil, path
+	}
+	for _, agent := range previewCrawlers {
+		if !robots.TestAgent(path, agent) {
+			report.add(SeverityError, "",
+				fmt.Sprintf("robots.txt doesn't let %s fetch the page.", agent),
+				fmt.Sprintf("Allow %s in robots.txt, since it only fetches pages people share.", agent))
+		}
+	}
+	return robots, path
+}
+
+// Fetches the page, if robots lets us, and runs the extractors over it.
+// Returns nil if there's nothing to look at.
+func (s *Scraper) validateFetch(ctx context.Context, report *ValidationReport, robots *robotstxt.RobotsData, path string) *Page {
+	if s.shouldCheckRobotsTxt && !s.robotsTxtPermits(robots, path) {
+		report.add(SeverityError, "",
+			fmt.Sprintf("robots.txt doesn't let us (%s) fetch the page, so there's nothing else to check.", s.useragent),
+			"Allow our user agent in robots.txt to see the rest of the report.")
+		return nil
+	}
+	resp, err := s.get(ctx, report.Url)
+	if err != nil {
+		report.add(SeverityError, "",
+			fmt.Sprintf("The page could not be fetched: %s", err),
+			"Make sure the page is public and returns a 200 to crawlers.")
+		return nil
+	}
+	defer resp.Body.Close()
+	contentType, body, err := sniffContentType(resp.Header.Get("Content-Type"), resp.Body)
+	if err != nil {
+		report.add(SeverityError, "", fmt.Sprintf("The page could not be read: %s", err), "Make sure the page loads in a browser.")
+		return nil
+	}
+	if !htmlContentTypes[contentType] {
+		report.add(SeverityInfo, "",
+			fmt.Sprintf("The url is %s rather than a web page, so it has no tags.", contentType),
+			"Share a page that links to it if you want control over the preview.")
+		return nil
+	}
+	doc, err := goquery.NewDocumentFromReader(body)
+	if err != nil {
+		report.add(SeverityError, "", fmt.Sprintf("The page could not be parsed: %s", err), "Make sure the page is valid HTML.")
+		return nil
+	}
+	page := s.newPage(doc, report.Url)
+	for _, problem := range s.runExtractors(page, nil) {
+		report.add(SeverityWarning, "", problem, "Check the markup the problem is about.")
+	}
+	return page
+}
+
+// Everything we can tell about the page without fetching anything else.
+func validateTags(page *Page, raw map[string][]string, aliases map[string][]string, report *ValidationReport) {
+	for _, required := range requiredTags {
+		if _, ok := raw[required.tag]; ok {
+			continue
+		}
+		if alias, ok := firstAlias(raw, aliases, required.tag); ok {
+			report.add(mildest(required.severity, SeverityWarning), required.tag,
+				fmt.Sprintf("%s is missing, %s is used instead.", required.tag, alias),
+				fmt.Sprintf("Add a %s tag so every site shows the same thing.", required.tag))
+			continue
+		}
+		report.add(required.severity, required.tag,
+			fmt.Sprintf("%s is missing. %s", required.tag, required.missing),
+			fmt.Sprintf("Add a <meta property=\"%s\" content=\"...\"> tag to the head of the page.", required.tag))
+	}
+
+	for _, tag := range singleValuedTags {
+		if values := raw[tag]; len(values) > 1 {
+			report.add(SeverityWarning, tag,
+				fmt.Sprintf("%s is on the page %d times with different values, only the first one (%q) is used.", tag, len(values), values[0]),
+				fmt.Sprintf("Remove all but one %s tag.", tag))
+		}
+	}
+
+	for _, tag := range append(append([]string{"article:published_time"}, aliases["article:published_time"]...), dateTags...) {
+		for _, value := range raw[tag] {
+			if _, ok := parseDate(value); !ok {
+				report.add(SeverityWarning, tag,
+					fmt.Sprintf("%q in %s is not a date we understand.", value, tag),
+					"Use an ISO 8601 date, like 2006-01-02T15:04:05Z.")
+			}
+		}
+	}
+
+	title := cardValue(raw, aliases, "og:title")
+	if length := utf8.RuneCountInString(title); length > maxTitleLength {
+		report.add(SeverityWarning, "og:title",
+			fmt.Sprintf("The title is %d characters long and will be cut off.", length),
+			fmt.Sprintf("Keep titles under %d characters.", maxTitleLength))
+	}
+
+	if ogUrl := cardValue(raw, nil, "og:url"); ogUrl != "" {
+		if canonical, ok := page.Doc.Find(`link[rel~="canonical"]`).Attr("href"); ok {
+			canonical, _ = resolveUrl(page.Url, canonical)
+			resolved, _ := resolveUrl(page.Url, ogUrl)
+			if normalizeComparableUrl(resolved) != normalizeComparableUrl(canonical) {
+				report.add(SeverityWarning, "og:url",
+					fmt.Sprintf("og:url is %s but the canonical url is %s.", ogUrl, canonical),
+					"Use the same url for both, otherwise likes and shares are split between them.")
+			}
+		}
+	}
+}
+
+// Checks the image the preview would use can be loaded and is big enough.
+func (s *Scraper) validateImage(ctx context.Context, report *ValidationReport) {
+	imageUrl := cardValue(report.Tags, s.aliases, "og:image")
+	if imageUrl == "" {
+		return
+	}
+	resolved, ok := resolveUrl(report.Url, imageUrl)
+	if !ok {
+		report.add(SeverityError, "og:image", fmt.Sprintf("%q is not a url.", imageUrl), "Use the full url of the image.")
+		return
+	}
+	if resolved != imageUrl {
+		report.add(SeverityWarning, "og:image",
+			fmt.Sprintf("The image url %s is relative.", imageUrl),
+			fmt.Sprintf("Use the full url, %s. Not every site resolves relative urls.", resolved))
+	}
+	info, err := s.probeImage(ctx, resolved)
+	if err != nil {
+		report.add(SeverityError, "og:image",
+			fmt.Sprintf("The image could not be loaded: %s", err),
+			"Make sure the image is public and is a JPEG, PNG, GIF or WebP.")
+		return
+	}
+	switch {
+	case info.Width == 0 || info.Height == 0:
+		report.add(SeverityInfo, "og:image",
+			fmt.Sprintf("Could not tell how big the %s image is.", info.ContentType),
+			"Not every site can show this kind of image, JPEG, PNG, GIF and WebP work everywhere.")
+	case info.Width < minImageWidth || info.Height < minImageHeight:
+		report.add(SeverityError, "og:image",
+			fmt.Sprintf("The image is %dx%d, which is too small to be shown.", info.Width, info.Height),
+			fmt.Sprintf("Use an image at least %dx%d, ideally %dx%d.", minImageWidth, minImageHeight, recommendedImageWidth, recommendedImageHeight))
+	case info.Width < recommendedImageWidth || info.Height < recommendedImageHeight:
+		report.add(SeverityInfo, "og:image",
+			fmt.Sprintf("The image is %dx%d, so it may be shown as a thumbnail.", info.Width, info.Height),
+			fmt.Sprintf("Use an image at least %dx%d for a big preview.", recommendedImageWidth, recommendedImageHeight))
+	}
+}
+
+// The first alias of tag that's on the page.
+func firstAlias(raw map[string][]string, aliases map[string][]string, tag string) (string, bool) {
+	for _, alias := range aliases[tag] {
+		if _, ok := raw[alias]; ok {
+			return alias, true
+		}
+	}
+	return "", false
+}
+
+// The value a card would get for tag.
+func cardValue(raw map[string][]string, aliases map[string][]string, tag string) string {
+	if values, ok := raw[tag]; ok {
+		return values[0]
+	}
+	if alias, ok := firstAlias(raw, aliases, tag); ok {
+		return raw[alias][0]
+	}
+	return ""
+}
+
+// Urls that only differ in ways nobody cares about, like a trailing slash,
+// count as the same.
+func normalizeComparableUrl(rawUrl string) string {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil {
+		return rawUrl
+	}
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Fragment = ""
+	parsed.Path = strings.TrimSuffix(parsed.Path, "/")
+	return parsed.String()
+}
@@ -0,0 +1,196 @@
+package gogetter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestValidateTags(t *testing.T) {
+	t.Parallel()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	doc := `<html><head>
+		<link rel="canonical" href="http://example.com/story" />
+		<meta property="og:type" content="article" />
+		<meta property="og:url" content="http://example.com/story?ref=home" />
+		<meta property="og:description" content="The first description" />
+		<meta property="og:description" content="The second description" />
+		<meta name="twitter:title" content="A title" />
+		<meta name="twitter:image" content="http://example.com/story.jpg" />
+		<meta property="og:updated_time" content="last tuesday" />
+	</head><body></body></html>`
+	parsed, err := goquery.NewDocumentFromReader(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := scraper.newPage(parsed, "http://example.com/story")
+	scraper.runExtractors(page, nil)
+	report := &ValidationReport{}
+	validateTags(page, page.RawTags(), scraper.aliases, report)
+	var problems []string
+	for _, finding := range report.Findings {
+		problems = append(problems, string(finding.Severity)+" "+finding.Tag)
+	}
+	expected := []string{
+		"warning og:title",
+		"warning og:image",
+		"warning og:description",
+		"warning og:updated_time",
+		"warning og:url",
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("%#v != %#v", problems, expected)
+	}
+}
+
+func TestValidateMissingTags(t *testing.T) {
+	t.Parallel()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	parsed, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><head></head><body></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := scraper.newPage(parsed, "http://example.com/")
+	report := &ValidationReport{}
+	validateTags(page, page.RawTags(), scraper.aliases, report)
+	severities := make(map[string]Severity)
+	for _, finding := range report.Findings {
+		severities[finding.Tag] = finding.Severity
+	}
+	expected := map[string]Severity{
+		"og:title":       SeverityError,
+		"og:image":       SeverityError,
+		"og:description": SeverityWarning,
+		"og:url":         SeverityWarning,
+		"og:type":        SeverityInfo,
+	}
+	if !reflect.DeepEqual(severities, expected) {
+		t.Errorf("%#v != %#v", severities, expected)
+	}
+}
+
+func TestValidateAliasSeverity(t *testing.T) {
+	t.Parallel()
+	scraper, err := NewScraper("", false)
+	if err != nil {
+		t.Fatalf("Could not create scraper: %s\n", err)
+	}
+	parsed, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><head></head><body></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := scraper.newPage(parsed, "http://example.com/")
+	raw := map[string][]string{
+		"schema:type":   {"article"},
+		"twitter:title": {"A title"},
+	}
+	report := &ValidationReport{}
+	validateTags(page, raw, scraper.aliases, report)
+	severities := make(map[string]Severity)
+	for _, finding := range report.Findings {
+		severities[finding.Tag] = finding.Severity
+	}
+	// og:type is only worth mentioning when it's missing entirely, so
+	// having an alias for it can't make things worse.
+	if severities["og:type"] != SeverityInfo {
+		t.Errorf("Expected og:type to be info, got %q", severities["og:type"])
+	}
+	if severities["og:title"] != SeverityWarning {
+		t.Errorf("Expected og:title to be a warning, got %q", severities["og:title"])
+	}
+}
+
+// Serves a page with the given robots.txt whose image is at imagePath, and
+// counts how often robots.txt is fetched.
+func newValidateServer(robots string, imagePath string, robotsRequests *int) *httptest.Server {
+	small := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	var smallPng bytes.Buffer
+	png.Encode(&smallPng, small)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			*robotsRequests++
+			w.Write([]byte(robots))
+		case "/story":
+			fmt.Fprintf(w, `<html><head>
+				<meta property="og:type" content="article" />
+				<meta property="og:title" content="A story" />
+				<meta property="og:description" content="About something" />
+				<meta property="og:url" content="http://%s/story" />
+				<meta property="og:image" content="http://%s%s" />
+			</head><body></body></html>`, r.Host, r.Host, imagePath)
+		case "/small.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(smallPng.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+// The findings in report whose problem starts with prefix.
+func findingsStartingWith(report *ValidationReport, prefix string) []Finding {
+	var findings []Finding
+	for _, finding := range report.Findings {
+		if strings.HasPrefix(finding.Problem, prefix) {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name      string
+		robots    string
+		imagePath string
+		problem   string
+		severity  Severity
+	}{
+		{"blocked crawler", "User-agent: Twitterbot\nDisallow: /\n", "/small.png", "robots.txt doesn't let Twitterbot fetch the page.", SeverityError},
+		{"blocked us", "User-agent: *\nDisallow: /story\n", "/small.png", "robots.txt doesn't let us (" + DEFAULT_UA + ") fetch the page", SeverityError},
+		{"unreachable image", "", "/missing.png", "The image could not be loaded:", SeverityError},
+		{"small image", "", "/small.png", "The image is 100x100, which is too small to be shown.", SeverityError},
+	}
+	for _, c := range cases {
+		robotsRequests := 0
+		server := newValidateServer(c.robots, c.imagePath, &robotsRequests)
+		scraper, err := NewScraper("", true)
+		if err != nil {
+			t.Fatalf("Could not create scraper: %s\n", err)
+		}
+		scraper.SetClient(server.Client())
+		report, err := scraper.Validate(context.Background(), server.URL+"/story")
+		server.Close()
+		if err != nil {
+			t.Errorf("%s: %s", c.name, err)
+			continue
+		}
+		findings := findingsStartingWith(report, c.problem)
+		if len(findings) != 1 || findings[0].Severity != c.severity {
+			t.Errorf("%s: expected a %s finding about %q, got %#v", c.name, c.severity, c.problem, report.Findings)
+		}
+		if fetchFailed := findingsStartingWith(report, "The page could not be fetched"); len(fetchFailed) != 0 {
+			t.Errorf("%s: unexpected %#v", c.name, fetchFailed)
+		}
+		if robotsRequests != 1 {
+			t.Errorf("%s: fetched robots.txt %d times", c.name, robotsRequests)
+		}
+	}
+}
@@ -0,0 +1,401 @@
+package gogetter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/JustinTulloss/gogetter/wildcard"
+)
+
+// How much of a video we read at a time looking for its header. The parts
+// we want come before the big sample tables, so even a truncated moov box is
+// usually enough.
+const maxVideoHeaderBytes = 256 * 1024
+
+// What we learned about a video from its container.
+type videoInfo struct {
+	Width  int
+	Height int
+	// In seconds
+	Duration float64
+	Codec    string
+}
+
+// Builds a video card for the video being read from body, which is the
+// response for url. MP4s that keep their moov box at the end get a second
+// request for just that part of the file.
+func (s *Scraper) scrapeVideo(body io.Reader, url, contentType string) (*wildcard.VideoCard, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(body, maxVideoHeaderBytes))
+	if err != nil {
+		return nil, err
+	}
+	card := wildcard.NewVideoCard(url)
+	card.Media.StreamUrl = url
+	card.Media.StreamContentType = contentType
+	var info *videoInfo
+	switch {
+	case len(data) >= 8 && string(data[4:8]) == "ftyp":
+		var moovAt int64
+		info, moovAt = readMP4(data)
+		if info == nil && moovAt > 0 {
+			byteRange := fmt.Sprintf("bytes=%d-%d", moovAt, moovAt+maxVideoHeaderBytes-1)
+			moov, err := s.fetchRange(url, byteRange, maxVideoHeaderBytes)
+			if err == nil {
+				info, _ = readMP4(moov)
+			}
+		}
+	case len(data) >= 4 && binary.BigEndian.Uint32(data) == ebmlHeader:
+		info = readWebM(data)
+	}
+	if info != nil {
+		if info.Width > 0 && info.Height > 0 {
+			card.Media.EmbeddedUrlWidth = strconv.Itoa(info.Width)
+			card.Media.EmbeddedUrlHeight = strconv.Itoa(info.Height)
+		}
+		card.Media.Duration = info.Duration
+		card.Media.Codec = info.Codec
+	}
+	return card, nil
+}
+
+// An ISO base media file box, which is what MP4 and friends are made of.
+type mp4Box struct {
+	Type    string
+	Payload []byte
+	// Where the next box starts, which can be past the end of what we have.
+	// -1 if this is the last box in the file.
+	End int64
+}
+
+// Reads the boxes in data one after another. The last one might be
+// truncated if data is only part of a file.
+func mp4Boxes(data []byte) []mp4Box {
+	var boxes []mp4Box
+	for offset := int64(0); offset+8 <= int64(len(data)); {
+		size := int64(binary.BigEndian.Uint32(data[offset : offset+4]))
+		kind := string(data[offset+4 : offset+8])
+		header := int64(8)
+		switch size {
+		case 0:
+			// The box runs to the end of the file, so there's nothing
+			// after it.
+			boxes = append(boxes, mp4Box{kind, data[offset+8:], -1})
+			return boxes
+		case 1:
+			if offset+16 > int64(len(data)) {
+				return boxes
+			}
+			size = int64(binary.BigEndian.Uint64(data[offset+8 : offset+16]))
+			header = 16
+		}
+		if size < header {
+			return boxes
+		}
+		end := offset + size
+		payloadEnd := end
+		if payloadEnd > int64(len(data)) || payloadEnd < 0 {
+			payloadEnd = int64(len(data))
+		}
+		if offset+header > payloadEnd {
+			return boxes
+		}
+		boxes = append(boxes, mp4Box{kind, data[offset+header : payloadEnd], end})
+		if end < 0 {
+			return boxes
+		}
+		offset = end
+	}
+	return boxes
+}
+
+// Looks for the moov box in data, which can either be the start of the file
+// or the moov box itself. If the moov box isn't there but we can tell where
+// it starts, that's returned so the caller can go get it.
+func readMP4(data []byte) (*videoInfo, int64) {
+	var next int64
+	for _, box := range mp4Boxes(data) {
+		if box.Type == "moov" {
+			return readMoov(box.Payload), 0
+		}
+		next = box.End
+	}
+	if next >= int64(len(data)) {
+		return nil, next
+	}
+	return nil, 0
+}
+
+func readMoov(moov []byte) *videoInfo {
+	info := &videoInfo{}
+	for _, box := range mp4Boxes(moov) {
+		switch box.Type {
+		case "mvhd":
+			info.Duration = mvhdDuration(box.Payload)
+		case "trak":
+			if readVideoTrak(box.Payload, info) {
+				return info
+			}
+		}
+	}
+	return info
+}
+
+func mvhdDuration(mvhd []byte) float64 {
+	var timescale uint32
+	var duration uint64
+	if len(mvhd) >= 32 && mvhd[0] == 1 {
+		timescale = binary.BigEndian.Uint32(mvhd[20:24])
+		duration = binary.BigEndian.Uint64(mvhd[24:32])
+	} else if len(mvhd) >= 20 {
+		timescale = binary.BigEndian.Uint32(mvhd[12:16])
+		duration = uint64(binary.BigEndian.Uint32(mvhd[16:20]))
+	}
+	if timescale == 0 {
+		return 0
+	}
+	return float64(duration) / float64(timescale)
+}
+
+// Fills in info from a track if it's a video track, and says whether it was.
+func readVideoTrak(trak []byte, info *videoInfo) bool {
+	var width, height int
+	var isVideo bool
+	var codec string
+	var sampleWidth, sampleHeight int
+	for _, box := range mp4Boxes(trak) {
+		switch box.Type {
+		case "tkhd":
+			width, height = tkhdSize(box.Payload)
+		case "mdia":
+			for _, mdia := range mp4Boxes(box.Payload) {
+				switch mdia.Type {
+				case "hdlr":
+					isVideo = len(mdia.Payload) >= 12 && string(mdia.Payload[8:12]) == "vide"
+				case "minf":
+					codec, sampleWidth, sampleHeight = minfSampleEntry(mdia.Payload)
+				}
+			}
+		}
+	}
+	if !isVideo {
+		return false
+	}
+	if width == 0 || height == 0 {
+		width, height = sampleWidth, sampleHeight
+	}
+	info.Width, info.Height, info.Codec = width, height, codec
+	return true
+}
+
+// The track header has the display size as 16.16 fixed point numbers at the
+// very end.
+func tkhdSize(tkhd []byte) (int, int) {
+	at := 76
+	if len(tkhd) > 0 && tkhd[0] == 1 {
+		at = 88
+	}
+	if len(tkhd) < at+8 {
+		return 0, 0
+	}
+	return int(binary.BigEndian.Uint32(tkhd[at:at+4]) >> 16), int(binary.BigEndian.Uint32(tkhd[at+4:at+8]) >> 16)
+}
+
+// Digs the first sample description out of minf/stbl/stsd. Its type is the
+// codec, and for video it also has the coded size.
+func minfSampleEntry(minf []byte) (string, int, int) {
+	for _, box := range mp4Boxes(minf) {
+		if box.Type != "stbl" {
+			continue
+		}
+		for _, stbl := range mp4Boxes(box.Payload) {
+			if stbl.Type != "stsd" || len(stbl.Payload) < 8 {
+				continue
+			}
+			entries := mp4Boxes(stbl.Payload[8:])
+			if len(entries) == 0 {
+				return "", 0, 0
+			}
+			entry := entries[0]
+			// Reserved, data reference index, then some more reserved
+			// and predefined fields before the size.
+			if len(entry.Payload) < 28 {
+				return entry.Type, 0, 0
+			}
+			width := int(binary.BigEndian.Uint16(entry.Payload[24:26]))
+			height := int(binary.BigEndian.Uint16(entry.Payload[26:28]))
+			return entry.Type, width, height
+		}
+	}
+	return "", 0, 0
+}
+
+// EBML element ids for the parts of a Matroska or WebM file we read.
+const (
+	ebmlHeader        = 0x1a45dfa3
+	ebmlSegment       = 0x18538067
+	ebmlInfo          = 0x1549a966
+	ebmlTimecodeScale = 0x2ad7b1
+	ebmlDuration      = 0x4489
+	ebmlTracks        = 0x1654ae6b
+	ebmlTrackEntry    = 0xae
+	ebmlTrackType     = 0x83
+	ebmlCodecID       = 0x86
+	ebmlVideo         = 0xe0
+	ebmlPixelWidth    = 0xb0
+	ebmlPixelHeight   = 0xba
+	ebmlDisplayWidth  = 0x54b0
+	ebmlDisplayHeight = 0x54ba
+
+	matroskaVideoTrack = 1
+)
+
+type ebmlElement struct {
+	ID   uint32
+	Data []byte
+}
+
+// Reads the elements in data one after another. Elements with an unknown
+// size, which live streams use for the segment, run to the end of data.
+func ebmlElements(data []byte) []ebmlElement {
+	var elements []ebmlElement
+	for offset := 0; offset < len(data); {
+		id, idLength := ebmlVarint(data[offset:], true)
+		if idLength == 0 {
+			break
+		}
+		size, sizeLength := ebmlVarint(data[offset+idLength:], false)
+		if sizeLength == 0 {
+			break
+		}
+		start := offset + idLength + sizeLength
+		end := len(data)
+		if size != ebmlUnknownSize(sizeLength) && uint64(start)+size < uint64(len(data)) {
+			end = start + int(size)
+		}
+		elements = append(elements, ebmlElement{uint32(id), data[start:end]})
+		offset = end
+	}
+	return elements
+}
+
+// EBML variable length integers say how long they are with the number of
+// leading zeros in the first byte. Ids keep the length marker, sizes don't.
+func ebmlVarint(data []byte, keepMarker bool) (uint64, int) {
+	if len(data) == 0 || data[0] == 0 {
+		return 0, 0
+	}
+	length := 1
+	for mask := byte(0x80); data[0]&mask == 0; mask >>= 1 {
+		length++
+	}
+	if length > 8 || length > len(data) {
+		return 0, 0
+	}
+	value := uint64(data[0])
+	if !keepMarker {
+		value &= uint64(0xff >> uint(length))
+	}
+	for _, b := range data[1:length] {
+		value = value<<8 | uint64(b)
+	}
+	return value, length
+}
+
+func ebmlUnknownSize(length int) uint64 {
+	return 1<<(7*uint(length)) - 1
+}
+
+func ebmlUint(data []byte) uint64 {
+	var value uint64
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
+
+func ebmlFloat(data []byte) float64 {
+	switch len(data) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data))
+	}
+	return 0
+}
+
+// Reads the segment info and tracks at the start of a WebM or Matroska file.
+// They come before the first cluster in every muxer we know of.
+func readWebM(data []byte) *videoInfo {
+	info := &videoInfo{}
+	for _, element := range ebmlElements(data) {
+		if element.ID != ebmlSegment {
+			continue
+		}
+		timecodeScale := uint64(1000000)
+		var duration float64
+		for _, child := range ebmlElements(element.Data) {
+			switch child.ID {
+			case ebmlInfo:
+				for _, field := range ebmlElements(child.Data) {
+					switch field.ID {
+					case ebmlTimecodeScale:
+						timecodeScale = ebmlUint(field.Data)
+					case ebmlDuration:
+						duration = ebmlFloat(field.Data)
+					}
+				}
+			case ebmlTracks:
+				readWebMTracks(child.Data, info)
+			}
+		}
+		info.Duration = duration * float64(timecodeScale) / 1e9
+	}
+	return info
+}
+
+func readWebMTracks(tracks []byte, info *videoInfo) {
+	for _, entry := range ebmlElements(tracks) {
+		if entry.ID != ebmlTrackEntry {
+			continue
+		}
+		var trackType uint64
+		var codec string
+		var width, height, displayWidth, displayHeight int
+		for _, field := range ebmlElements(entry.Data) {
+			switch field.ID {
+			case ebmlTrackType:
+				trackType = ebmlUint(field.Data)
+			case ebmlCodecID:
+				codec = strings.TrimRight(string(field.Data), "\x00")
+			case ebmlVideo:
+				for _, video := range ebmlElements(field.Data) {
+					switch video.ID {
+					case ebmlPixelWidth:
+						width = int(ebmlUint(video.Data))
+					case ebmlPixelHeight:
+						height = int(ebmlUint(video.Data))
+					case ebmlDisplayWidth:
+						displayWidth = int(ebmlUint(video.Data))
+					case ebmlDisplayHeight:
+						displayHeight = int(ebmlUint(video.Data))
+					}
+				}
+			}
+		}
+		if trackType != matroskaVideoTrack {
+			continue
+		}
+		// The display size is what it should look like, which is what we
+		// care about for getting the aspect ratio right.
+		if displayWidth > 0 && displayHeight > 0 {
+			width, height = displayWidth, displayHeight
+		}
+		info.Width, info.Height, info.Codec = width, height, codec
+		return
+	}
+}
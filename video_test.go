@@ -0,0 +1,100 @@
+package gogetter
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func box(kind string, payload ...[]byte) []byte {
+	var body []byte
+	for _, p := range payload {
+		body = append(body, p...)
+	}
+	b := binary.BigEndian.AppendUint32(nil, uint32(len(body)+8))
+	return append(append(b, kind...), body...)
+}
+
+func ebml(id uint32, data ...[]byte) []byte {
+	var body []byte
+	for _, d := range data {
+		body = append(body, d...)
+	}
+	var b []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if c := byte(id >> uint(shift)); c != 0 || len(b) > 0 {
+			b = append(b, c)
+		}
+	}
+	// An eight byte size is always big enough: a marker byte and seven
+	// bytes of length.
+	b = append(b, 0x01)
+	b = append(b, binary.BigEndian.AppendUint64(nil, uint64(len(body)))[1:]...)
+	return append(b, body...)
+}
+
+func TestReadMP4(t *testing.T) {
+	t.Parallel()
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:16], 1000)
+	binary.BigEndian.PutUint32(mvhd[16:20], 90500)
+	tkhd := make([]byte, 84)
+	binary.BigEndian.PutUint32(tkhd[76:80], 1280<<16)
+	binary.BigEndian.PutUint32(tkhd[80:84], 720<<16)
+	hdlr := append(make([]byte, 8), "vide"...)
+	hdlr = append(hdlr, make([]byte, 13)...)
+	avc1 := make([]byte, 78)
+	binary.BigEndian.PutUint16(avc1[24:26], 1920)
+	binary.BigEndian.PutUint16(avc1[26:28], 1080)
+	stsd := append(binary.BigEndian.AppendUint32(make([]byte, 4), 1), box("avc1", avc1)...)
+	moov := box("moov",
+		box("mvhd", mvhd),
+		box("trak",
+			box("tkhd", tkhd),
+			box("mdia", box("hdlr", hdlr), box("minf", box("stbl", box("stsd", stsd)))),
+		),
+	)
+	expected := &videoInfo{Width: 1280, Height: 720, Duration: 90.5, Codec: "avc1"}
+
+	fastStart := append(box("ftyp", []byte("isom")), moov...)
+	info, _ := readMP4(fastStart)
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("%#v != %#v", info, expected)
+	}
+
+	// When the moov box comes after the media we should be told where it is.
+	head := append(box("ftyp", []byte("isom")), box("mdat", make([]byte, 1000))...)
+	info, moovAt := readMP4(head[:100])
+	if info != nil || moovAt != int64(len(head)) {
+		t.Errorf("Expected moov at %d, got %d", len(head), moovAt)
+	}
+	info, _ = readMP4(moov)
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("%#v != %#v", info, expected)
+	}
+}
+
+func TestReadWebM(t *testing.T) {
+	t.Parallel()
+	duration := binary.BigEndian.AppendUint64(nil, math.Float64bits(12500))
+	webm := append(
+		ebml(ebmlHeader, ebml(0x4282, []byte("webm"))),
+		ebml(ebmlSegment,
+			ebml(ebmlInfo, ebml(ebmlTimecodeScale, []byte{0x0f, 0x42, 0x40}), ebml(ebmlDuration, duration)),
+			ebml(ebmlTracks,
+				ebml(ebmlTrackEntry, ebml(ebmlTrackType, []byte{2}), ebml(ebmlCodecID, []byte("A_OPUS"))),
+				ebml(ebmlTrackEntry,
+					ebml(ebmlTrackType, []byte{matroskaVideoTrack}),
+					ebml(ebmlCodecID, []byte("V_VP9")),
+					ebml(ebmlVideo, ebml(ebmlPixelWidth, []byte{0x02, 0x80}), ebml(ebmlPixelHeight, []byte{0x01, 0x68})),
+				),
+			),
+		)...,
+	)
+	expected := &videoInfo{Width: 640, Height: 360, Duration: 12.5, Codec: "V_VP9"}
+	info := readWebM(webm)
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("%#v != %#v", info, expected)
+	}
+}
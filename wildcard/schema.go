@@ -22,6 +22,11 @@ const (
 	ProductType       CardType = "product"
 	ReviewType        CardType = "review"
 	VideoType         CardType = "video"
+
+	// Our own additions, for PDFs and the like, and for RSS, Atom and JSON
+	// feeds
+	DocumentType CardType = "document"
+	FeedType     CardType = "feed"
 )
 
 type MediaType string
@@ -31,13 +36,126 @@ const (
 	VideoMediaType MediaType = "video"
 )
 
-// Every card must implement this interface
-type Wildcard interface{}
+// Every card must implement this interface. It used to be empty, so card
+// types from outside this package need these methods now, see the README.
+type Wildcard interface {
+	// Gives access to the fields every card has without having to know
+	// what kind of card it is.
+	BaseCard() *Card
+	// Same idea for the metadata every topic has. Can be nil if the card
+	// doesn't have a topic yet.
+	Metadata() *GenericMetadata
+	// Checks the card has everything wildcard requires of it. Returns
+	// nothing for a valid card.
+	Validate() []Violation
+}
+
+// The url of whatever the card is about, if it has one apart from the
+// card's WebUrl.
+func TopicUrl(card Wildcard) string {
+	switch c := card.(type) {
+	case *ArticleCard:
+		if c.Article != nil {
+			return c.Article.Url
+		}
+	case *LinkCard:
+		if c.Target != nil {
+			return c.Target.Url
+		}
+	case *DocumentCard:
+		if c.Document != nil {
+			return c.Document.Url
+		}
+	case *FeedCard:
+		if c.Feed != nil {
+			return c.Feed.Url
+		}
+	case *PlaceCard:
+		if c.Place != nil {
+			return c.Place.Url
+		}
+	case *ProductCard:
+		if c.Product != nil {
+			return c.Product.Url
+		}
+	}
+	return ""
+}
+
+// Whatever passes for a description of the card's topic. Every kind of card
+// calls it something different.
+func TopicDescription(card Wildcard) string {
+	switch c := card.(type) {
+	case *ArticleCard:
+		if c.Article != nil {
+			return c.Article.AbstractContent
+		}
+	case *VideoCard:
+		if c.Media != nil {
+			return c.Media.Description
+		}
+	case *ImageCard:
+		if c.Media != nil {
+			return c.Media.ImageCaption
+		}
+	case *LinkCard:
+		if c.Target != nil {
+			return c.Target.Description
+		}
+	case *DocumentCard:
+		if c.Document != nil {
+			return c.Document.Subject
+		}
+	case *FeedCard:
+		if c.Feed != nil {
+			return c.Feed.Description
+		}
+	case *PlaceCard:
+		if c.Place != nil {
+			return c.Place.Description
+		}
+	case *ProductCard:
+		if c.Product != nil {
+			return c.Product.Description
+		}
+	}
+	return ""
+}
 
 // Every card has these
 type Card struct {
 	CardType CardType `json:"card_type"`
 	WebUrl   string   `json:"web_url"`
+
+	// Our own addition, problems we ran into filling out the card that
+	// didn't stop us from producing it.
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Our own addition, only filled in when asked for. Where every field
+	// on the card came from, keyed by where the field is in the JSON, like
+	// "article.title".
+	Provenance map[string]*Provenance `json:"provenance,omitempty"`
+}
+
+func (c *Card) BaseCard() *Card {
+	return c
+}
+
+// Where a field came from, for working out why a card says what it does.
+type Provenance struct {
+	// The tag the value was read from. Empty for fields filled in without
+	// going through the tags.
+	Tag string `json:"tag,omitempty"`
+	// What found it
+	Extractor string `json:"extractor,omitempty"`
+	// Other tags that had a value for the field, and lost
+	Competing []CompetingValue `json:"competing,omitempty"`
+}
+
+type CompetingValue struct {
+	Tag       string `json:"tag"`
+	Value     string `json:"value"`
+	Extractor string `json:"extractor,omitempty"`
 }
 
 // Metadata that pretty much every topic has
@@ -45,16 +163,31 @@ type GenericMetadata struct {
 	Title           string     `json:"title,omitempty" ogtag:"og:title"`
 	PublicationDate *time.Time `json:"publication_date,omitempty" ogtag:"article:published_time"`
 	Source          string     `json:"source,omitempty" ogtag:"og:site_name"`
-	Keywords        []string   `json:"keywords,omitempty"`
+	Keywords        []string   `json:"keywords,omitempty" ogtag:"keywords"`
 
 	// Our own addition, wildcard has a neutered version
-	AppLink *applink.AppLink `json:"app_link,omitempty" ogtag:",fill"`
+	AppLink *applink.AppLink `json:"app_link,omitempty"`
 
 	// Our own addition, is usually the favicon
 	SourceIcon string `json:"source_icon,omitempty" ogtag:"favicon"`
 
 	// Our own addition since why wouldn't everything have an image?
 	Image *ImageDetails `json:"image,omitempty" ogtag:",fill"`
+
+	// Our own addition, only filled in when we had to go digging through
+	// the page to find a description.
+	WordCount int `json:"word_count,omitempty" ogtag:"content:word_count"`
+	// In minutes
+	ReadingTime int `json:"reading_time,omitempty" ogtag:"content:reading_time"`
+
+	// Our own addition, the feeds the page says you can subscribe to
+	Feeds []FeedLink `json:"feeds,omitempty"`
+}
+
+type FeedLink struct {
+	Url         string `json:"url"`
+	ContentType string `json:"content_type,omitempty"`
+	Title       string `json:"title,omitempty"`
 }
 
 type Article struct {
@@ -63,9 +196,24 @@ type Article struct {
 	IsBreaking      bool     `json:"is_breaking,omitempty"`
 	Contributors    []string `json:"contributors,omitempty"`
 	Byline          string   `json:"byline,omitempty" ogtag:"byl"`
+	// Our own addition, for scholarly articles
+	Citation        *Citation `json:"citation,omitempty" ogtag:",fill"`
 	GenericMetadata `ogtag:",squash"`
 }
 
+// Where a scholarly article was published and how to cite it, from the
+// Highwire Press citation_* tags journals and preprint servers use.
+type Citation struct {
+	Doi       string   `json:"doi,omitempty" ogtag:"citation_doi"`
+	Journal   string   `json:"journal,omitempty" ogtag:"citation_journal_title"`
+	Publisher string   `json:"publisher,omitempty" ogtag:"citation_publisher"`
+	Volume    string   `json:"volume,omitempty" ogtag:"citation_volume"`
+	Issue     string   `json:"issue,omitempty" ogtag:"citation_issue"`
+	Authors   []string `json:"authors,omitempty"`
+	// Where to get the full text
+	PdfUrl string `json:"pdf_url,omitempty" ogtag:"citation_pdf_url"`
+}
+
 type ArticleCard struct {
 	Card
 	Article *Article `json:"article" ogtag:",fill"`
@@ -83,11 +231,18 @@ func NewArticleCard(webUrl, articleUrl string) *ArticleCard {
 	}
 }
 
+func (c *ArticleCard) Metadata() *GenericMetadata {
+	if c.Article == nil {
+		return nil
+	}
+	return &c.Article.GenericMetadata
+}
+
 type VideoMedia struct {
 	Type MediaType `json:"type"`
 
 	// XXX: Perhaps pull these out for other embed types
-	EmbeddedUrl       string `json:"embedded_url"`
+	EmbeddedUrl       string `json:"embedded_url" ogtag:"twitter:player"`
 	EmbeddedUrlWidth  string `json:"embedded_url_width" ogtag:"og:video:width"`
 	EmbeddedUrlHeight string `json:"embedded_url_height" ogtag:"og:video:height"`
 
@@ -98,6 +253,10 @@ type VideoMedia struct {
 	PosterImageUrl    string `json:"poster_image_url,omitempty" ogtag:"og:image:url"`
 	Creator           string `json:"creator,omitempty"`
 	GenericMetadata   `ogtag:",squash"`
+
+	// Our own additions, from the video file itself. Duration is in seconds.
+	Duration float64 `json:"duration,omitempty"`
+	Codec    string  `json:"codec,omitempty"`
 }
 
 type VideoCard struct {
@@ -117,6 +276,13 @@ func NewVideoCard(originalUrl string) *VideoCard {
 	}
 }
 
+func (c *VideoCard) Metadata() *GenericMetadata {
+	if c.Media == nil {
+		return nil
+	}
+	return &c.Media.GenericMetadata
+}
+
 type ImageDetails struct {
 	ImageUrl string `json:"image_url" ogtag:"og:image"`
 	Width    int    `json:"width,omitempty" ogtag:"og:image:width"`
@@ -134,6 +300,11 @@ type ImageMedia struct {
 	ImageCaption string `json:"image_caption,omitempty"`
 	Author       string `json:"author,omitempty"`
 	GenericMetadata
+
+	// Our own additions, from the image's EXIF, IPTC or XMP metadata
+	Copyright   string          `json:"copyright,omitempty"`
+	CaptureDate *time.Time      `json:"capture_date,omitempty"`
+	Location    *GeoCoordinates `json:"location,omitempty"`
 }
 
 type ImageCard struct {
@@ -156,6 +327,13 @@ func NewImageCard(originalUrl, src string) *ImageCard {
 	}
 }
 
+func (c *ImageCard) Metadata() *GenericMetadata {
+	if c.Media == nil {
+		return nil
+	}
+	return &c.Media.GenericMetadata
+}
+
 type LinkTarget struct {
 	Url             string `json:"url"`
 	Description     string `json:"description,omitempty" ogtag:"og:description"`
@@ -179,16 +357,98 @@ func NewLinkCard(originalUrl, linkUrl string) *LinkCard {
 	}
 }
 
+func (c *LinkCard) Metadata() *GenericMetadata {
+	if c.Target == nil {
+		return nil
+	}
+	return &c.Target.GenericMetadata
+}
+
+// Our own addition, wildcard doesn't have anything for documents.
+type Document struct {
+	Url         string `json:"url"`
+	ContentType string `json:"content_type,omitempty"`
+	Author      string `json:"author,omitempty"`
+	Subject     string `json:"subject,omitempty"`
+	PageCount   int    `json:"page_count,omitempty"`
+	GenericMetadata
+}
+
+type DocumentCard struct {
+	Card
+	Document *Document `json:"document"`
+}
+
+func NewDocumentCard(webUrl, documentUrl string) *DocumentCard {
+	return &DocumentCard{
+		Card{
+			CardType: DocumentType,
+			WebUrl:   webUrl,
+		},
+		&Document{
+			Url: documentUrl,
+		},
+	}
+}
+
+func (c *DocumentCard) Metadata() *GenericMetadata {
+	if c.Document == nil {
+		return nil
+	}
+	return &c.Document.GenericMetadata
+}
+
+// Our own addition, wildcard doesn't have anything for feeds.
+type FeedItem struct {
+	Url             string     `json:"url,omitempty"`
+	Title           string     `json:"title,omitempty"`
+	Description     string     `json:"description,omitempty"`
+	PublicationDate *time.Time `json:"publication_date,omitempty"`
+}
+
+type Feed struct {
+	Url         string `json:"url"`
+	Description string `json:"description,omitempty"`
+	// One of rss, atom or json
+	Format string     `json:"format"`
+	Items  []FeedItem `json:"items,omitempty"`
+	GenericMetadata
+}
+
+type FeedCard struct {
+	Card
+	Feed *Feed `json:"feed"`
+}
+
+func NewFeedCard(webUrl, feedUrl string) *FeedCard {
+	return &FeedCard{
+		Card{
+			CardType: FeedType,
+			WebUrl:   webUrl,
+		},
+		&Feed{
+			Url: feedUrl,
+		},
+	}
+}
+
+func (c *FeedCard) Metadata() *GenericMetadata {
+	if c.Feed == nil {
+		return nil
+	}
+	return &c.Feed.GenericMetadata
+}
+
 // Like, where to send snail mail. Quite possibly a physical address.
 type PostalAddress struct {
-	StreetAddress       string `json:"street_address"`
-	PostOfficeBoxNumber string `json:"post_office_box_number,omitempty"`
+	StreetAddress       string `json:"street_address" ogtag:"business:contact_data:street_address"`
+	PostOfficeBoxNumber string `json:"post_office_box_number,omitempty" ogtag:"business:contact_data:post_office_box_number"`
 	// In the US, this is the city
-	Locality string `json:"locality,omitempty"`
+	Locality string `json:"locality,omitempty" ogtag:"business:contact_data:locality"`
 	// In the US, this is the state
-	Region     string `json:"region,omitempty"`
-	PostalCode string `json:"postal_code,omitempty"`
-	Country    string `json:"country,omitempty"`
+	Region     string `json:"region,omitempty" ogtag:"business:contact_data:region"`
+	PostalCode string `json:"postal_code,omitempty" ogtag:"business:contact_data:postal_code"`
+	Country    string `json:"country,omitempty" ogtag:"business:contact_data:country_name"`
 }
 
 // Returns the address as a nicely formatted string on a single line.
@@ -211,25 +471,25 @@ func (a *PostalAddress) MultiLineFormatted() string {
 }
 
 type GeoCoordinates struct {
-	Latitude  *float64 `json:"latitude"`
-	Longitude *float64 `json:"longitude"`
-	Elevation *float64 `json:"elevation,omitempty"`
+	Latitude  *float64 `json:"latitude" ogtag:"place:location:latitude"`
+	Longitude *float64 `json:"longitude" ogtag:"place:location:longitude"`
+	Elevation *float64 `json:"elevation,omitempty" ogtag:"place:location:altitude"`
 }
 
 type Rating struct {
 	// What this is actually rated.
-	Value string `json:"value"`
+	Value string `json:"value" ogtag:"rating:value"`
 
 	// If this thing is perfect, this is what it would be rated.
-	BestRating string `json:"best_rating,omitempty"`
+	BestRating string `json:"best_rating,omitempty" ogtag:"rating:best"`
 
 	// This is almost always 1 (and should be assumed to be 1 if it's missing),
 	// but it's the minimum rating.
-	WorstRating string `json:"worst_rating,omitempty"`
+	WorstRating string `json:"worst_rating,omitempty" ogtag:"rating:worst"`
 
 	// Using an int32 here even though it limits things to 4 billion ratings.
-	RatingCount int32 `json:"rating_count,omitempty"`
-	ReviewCount int32 `json:"review_count,omitempty"`
+	RatingCount int32 `json:"rating_count,omitempty" ogtag:"rating:count"`
+	ReviewCount int32 `json:"review_count,omitempty" ogtag:"rating:review_count"`
 
 	// An image that can be used to represent this rating.
 	ImageUrl string `json:"image_url,omitempty"`
@@ -275,14 +535,14 @@ type Hours struct {
 
 type Place struct {
 	Url         string `json:"url,omitempty"`
-	Description string `json:"description,omitempty"`
+	Description string `json:"description,omitempty" ogtag:"og:description"`
 
 	// Despite the "PostalAddress" type, this should be a physical address.
-	Address              *PostalAddress  `json:"address,omitempty"`
-	Location             *GeoCoordinates `json:"location,omitempty"`
-	Rating               *Rating         `json:"rating,omitempty"`
+	Address              *PostalAddress  `json:"address,omitempty" ogtag:",fill"`
+	Location             *GeoCoordinates `json:"location,omitempty" ogtag:",fill"`
+	Rating               *Rating         `json:"rating,omitempty" ogtag:",fill"`
 	Hours                *Hours          `json:"hours,omitempty"`
-	PhoneNumber          string          `json:"phone_number,omitempty"`
+	PhoneNumber          string          `json:"phone_number,omitempty" ogtag:"business:contact_data:phone_number"`
 	FormattedPhoneNumber string          `json:"formatted_phone_number,omitempty"`
 	GenericMetadata      `ogtag:",squash"`
 }
@@ -295,7 +555,7 @@ func (p *Place) HasLocation() bool {
 
 type PlaceCard struct {
 	Card
-	Place *Place `json:"place"`
+	Place *Place `json:"place" ogtag:",fill"`
 }
 
 func NewPlaceCard(webUrl string) *PlaceCard {
@@ -307,3 +567,49 @@ func NewPlaceCard(webUrl string) *PlaceCard {
 		&Place{},
 	}
 }
+
+func (c *PlaceCard) Metadata() *GenericMetadata {
+	if c.Place == nil {
+		return nil
+	}
+	return &c.Place.GenericMetadata
+}
+
+// Wildcard's product card has a lot more to it, but this is what pages tell
+// us about the things they sell.
+type Product struct {
+	Url          string `json:"url"`
+	Description  string `json:"description,omitempty" ogtag:"og:description"`
+	Brand        string `json:"brand,omitempty" ogtag:"product:brand"`
+	Sku          string `json:"sku,omitempty" ogtag:"product:retailer_item_id"`
+	Price        string `json:"price,omitempty" ogtag:"product:price:amount"`
+	Currency     string `json:"currency,omitempty" ogtag:"product:price:currency"`
+	Availability string `json:"availability,omitempty" ogtag:"product:availability"`
+	// The overall rating from everyone who reviewed it.
+	Rating          *Rating `json:"rating,omitempty" ogtag:",fill"`
+	GenericMetadata `ogtag:",squash"`
+}
+
+type ProductCard struct {
+	Card
+	Product *Product `json:"product" ogtag:",fill"`
+}
+
+func NewProductCard(webUrl, productUrl string) *ProductCard {
+	return &ProductCard{
+		Card{
+			CardType: ProductType,
+			WebUrl:   webUrl,
+		},
+		&Product{
+			Url: productUrl,
+		},
+	}
+}
+
+func (c *ProductCard) Metadata() *GenericMetadata {
+	if c.Product == nil {
+		return nil
+	}
+	return &c.Product.GenericMetadata
+}
@@ -0,0 +1,204 @@
+package wildcard
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Something about a card that wildcard won't accept.
+type Violation struct {
+	// Where the field is in the JSON, like "media.embedded_url"
+	Field   string `json:"field"`
+	Problem string `json:"problem"`
+}
+
+// The problem with fields that have to be there and aren't.
+const MissingProblem = "is required"
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s %s", v.Field, v.Problem)
+}
+
+// Collects violations as a card is checked, with the path to whatever's
+// being checked at the moment.
+type validator struct {
+	path       string
+	violations []Violation
+}
+
+func (v *validator) at(field string) *validator {
+	path := field
+	if v.path != "" {
+		path = v.path + "." + field
+	}
+	return &validator{path: path}
+}
+
+// Folds in what a validator from at found.
+func (v *validator) merge(other *validator) {
+	v.violations = append(v.violations, other.violations...)
+}
+
+func (v *validator) add(field, problem string) {
+	if v.path != "" {
+		field = v.path + "." + field
+	}
+	v.violations = append(v.violations, Violation{Field: field, Problem: problem})
+}
+
+func (v *validator) required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(field, MissingProblem)
+	}
+}
+
+// Checks a part of the card that has to be there.
+func (v *validator) present(field string, missing bool) bool {
+	if missing {
+		v.add(field, MissingProblem)
+		return false
+	}
+	return true
+}
+
+func (v *validator) card(c *Card, cardType CardType) {
+	if c.CardType != cardType {
+		v.add("card_type", fmt.Sprintf("is %q instead of %q", c.CardType, cardType))
+	}
+	v.required("web_url", c.WebUrl)
+}
+
+func (v *validator) metadata(m *GenericMetadata) {
+	if m.Image != nil {
+		image := v.at("image")
+		image.imageDetails(m.Image)
+		v.merge(image)
+	}
+	for i, feed := range m.Feeds {
+		v.required(fmt.Sprintf("feeds.%d.url", i), feed.Url)
+	}
+}
+
+func (v *validator) imageDetails(i *ImageDetails) {
+	v.required("image_url", i.ImageUrl)
+}
+
+func (c *ArticleCard) Validate() []Violation {
+	v := &validator{}
+	v.card(&c.Card, ArticleType)
+	if v.present("article", c.Article == nil) {
+		article := v.at("article")
+		article.required("url", c.Article.Url)
+		article.required("abstract_content", c.Article.AbstractContent)
+		article.metadata(&c.Article.GenericMetadata)
+		v.merge(article)
+	}
+	return v.violations
+}
+
+func (c *VideoCard) Validate() []Violation {
+	v := &validator{}
+	v.card(&c.Card, VideoType)
+	if v.present("media", c.Media == nil) {
+		media := v.at("media")
+		if c.Media.Type != VideoMediaType {
+			media.add("type", fmt.Sprintf("is %q instead of %q", c.Media.Type, VideoMediaType))
+		}
+		media.required("embedded_url", c.Media.EmbeddedUrl)
+		media.required("embedded_url_width", c.Media.EmbeddedUrlWidth)
+		media.required("embedded_url_height", c.Media.EmbeddedUrlHeight)
+		media.metadata(&c.Media.GenericMetadata)
+		v.merge(media)
+	}
+	return v.violations
+}
+
+func (c *ImageCard) Validate() []Violation {
+	v := &validator{}
+	v.card(&c.Card, ImageType)
+	if v.present("media", c.Media == nil) {
+		media := v.at("media")
+		if c.Media.Type != ImageMediaType {
+			media.add("type", fmt.Sprintf("is %q instead of %q", c.Media.Type, ImageMediaType))
+		}
+		media.imageDetails(&c.Media.ImageDetails)
+		media.metadata(&c.Media.GenericMetadata)
+		v.merge(media)
+	}
+	return v.violations
+}
+
+func (c *LinkCard) Validate() []Violation {
+	v := &validator{}
+	v.card(&c.Card, LinkType)
+	if v.present("target", c.Target == nil) {
+		target := v.at("target")
+		target.required("url", c.Target.Url)
+		target.metadata(&c.Target.GenericMetadata)
+		v.merge(target)
+	}
+	return v.violations
+}
+
+func (c *DocumentCard) Validate() []Violation {
+	v := &validator{}
+	v.card(&c.Card, DocumentType)
+	if v.present("document", c.Document == nil) {
+		document := v.at("document")
+		document.required("url", c.Document.Url)
+		document.metadata(&c.Document.GenericMetadata)
+		v.merge(document)
+	}
+	return v.violations
+}
+
+func (c *FeedCard) Validate() []Violation {
+	v := &validator{}
+	v.card(&c.Card, FeedType)
+	if v.present("feed", c.Feed == nil) {
+		feed := v.at("feed")
+		feed.required("url", c.Feed.Url)
+		feed.required("format", c.Feed.Format)
+		feed.metadata(&c.Feed.GenericMetadata)
+		v.merge(feed)
+	}
+	return v.violations
+}
+
+func (c *PlaceCard) Validate() []Violation {
+	v := &validator{}
+	v.card(&c.Card, PlaceType)
+	if v.present("place", c.Place == nil) {
+		place := v.at("place")
+		if c.Place.Address != nil {
+			place.required("address.street_address", c.Place.Address.StreetAddress)
+		}
+		if c.Place.Location != nil && !c.Place.HasLocation() {
+			place.add("location", "needs both a latitude and a longitude")
+		}
+		if c.Place.Rating != nil {
+			place.required("rating.value", c.Place.Rating.Value)
+		}
+		if c.Place.Hours != nil && len(c.Place.Hours.Days) != len(c.Place.Hours.Open) {
+			place.add("hours", "needs opening times for every day")
+		}
+		place.metadata(&c.Place.GenericMetadata)
+		v.merge(place)
+	}
+	return v.violations
+}
+
+func (c *ProductCard) Validate() []Violation {
+	v := &validator{}
+	v.card(&c.Card, ProductType)
+	if v.present("product", c.Product == nil) {
+		product := v.at("product")
+		product.required("url", c.Product.Url)
+		if c.Product.Rating != nil {
+			product.required("rating.value", c.Product.Rating.Value)
+		}
+		product.metadata(&c.Product.GenericMetadata)
+		v.merge(product)
+	}
+	return v.violations
+}
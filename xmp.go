@@ -0,0 +1,103 @@
+package gogetter
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+)
+
+// XMP namespaces we know how to read, and the prefix we use for them in
+// property keys. Files can bind these to any prefix they like, so we go by
+// the namespace rather than what the file calls it.
+var xmpNamespaces = map[string]string{
+	"http://purl.org/dc/elements/1.1/":            "dc",
+	"http://ns.adobe.com/xap/1.0/":                "xmp",
+	"http://ns.adobe.com/pdf/1.3/":                "pdf",
+	"http://ns.adobe.com/photoshop/1.0/":          "photoshop",
+	"http://ns.adobe.com/exif/1.0/":               "exif",
+	"http://ns.adobe.com/tiff/1.0/":               "tiff",
+	"http://iptc.org/std/Iptc4xmpCore/1.0/xmlns/": "Iptc4xmpCore",
+}
+
+const rdfNamespace = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+
+var (
+	xmpStart = []byte("<x:xmpmeta")
+	xmpEnd   = []byte("</x:xmpmeta>")
+)
+
+// Finds the first XMP packet in data, which might be a whole file or just
+// the start of one. Returns nil if there isn't a complete packet.
+func findXMPPacket(data []byte) []byte {
+	start := bytes.Index(data, xmpStart)
+	if start < 0 {
+		return nil
+	}
+	end := bytes.Index(data[start:], xmpEnd)
+	if end < 0 {
+		return nil
+	}
+	return data[start : start+end+len(xmpEnd)]
+}
+
+// Reads the properties out of an XMP packet, keyed like "dc:title". XMP lets
+// a property be written as an attribute or an element, and lets element
+// values be a plain string or an rdf:Alt, rdf:Bag or rdf:Seq of them, so
+// every value comes back as a list.
+func readXMP(packet []byte) map[string][]string {
+	properties := make(map[string][]string)
+	decoder := xml.NewDecoder(bytes.NewReader(packet))
+	decoder.Strict = false
+	var open []string
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			key := xmpKey(token.Name)
+			if token.Name.Space == rdfNamespace {
+				key = rdfContainer
+			}
+			open = append(open, key)
+			for _, attr := range token.Attr {
+				if attrKey := xmpKey(attr.Name); attrKey != "" && attr.Value != "" {
+					properties[attrKey] = append(properties[attrKey], attr.Value)
+				}
+			}
+		case xml.EndElement:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		case xml.CharData:
+			text := strings.TrimSpace(string(token))
+			if text == "" {
+				continue
+			}
+			// The text belongs to the closest property around it, which
+			// skips over any rdf:li and friends in between.
+			for i := len(open) - 1; i >= 0 && open[i] != ""; i-- {
+				if open[i] != rdfContainer {
+					properties[open[i]] = append(properties[open[i]], text)
+					break
+				}
+			}
+		}
+	}
+	return properties
+}
+
+// Stands in for rdf:Alt, rdf:li and the like while we keep track of which
+// elements are open, since their text belongs to the property around them.
+const rdfContainer = "rdf"
+
+// Turns an element or attribute name into a property key, or "" if it's not
+// a property we know about.
+func xmpKey(name xml.Name) string {
+	prefix, ok := xmpNamespaces[name.Space]
+	if !ok {
+		return ""
+	}
+	return prefix + ":" + name.Local
+}